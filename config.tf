@@ -339,7 +339,17 @@ variable "tectonic_ignition_worker" {
   default = ""
 
   description = <<EOF
-(internal) Ignition config file path. This is automatically generated by the installer.
+(deprecated) Ignored. Every worker pool has an ignition config file of its own, see tectonic_worker_pools.
+EOF
+}
+
+variable "tectonic_worker_pools" {
+  type    = "list"
+  default = []
+
+  description = <<EOF
+(internal) The worker pools, each a map with the name, count and ignition config file path of the pool,
+and its platform settings overriding those of the workers. This is automatically generated by the installer.
 EOF
 }
 
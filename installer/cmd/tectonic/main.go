@@ -1,17 +1,24 @@
 package main
 
 import (
+	"context"
 	"os"
+	"os/signal"
+	"syscall"
 
 	log "github.com/Sirupsen/logrus"
 	"gopkg.in/alecthomas/kingpin.v2"
 
+	"github.com/coreos/tectonic-installer/installer/pkg/wizard"
 	"github.com/coreos/tectonic-installer/installer/pkg/workflow"
 )
 
 var (
-	clusterInitCommand    = kingpin.Command("init", "Initialize a new Tectonic cluster")
-	clusterInitConfigFlag = clusterInitCommand.Flag("config", "Cluster specification file").Required().ExistingFile()
+	clusterInitCommand         = kingpin.Command("init", "Initialize a new Tectonic cluster")
+	clusterInitConfigFlag      = clusterInitCommand.Flag("config", "Cluster specification file; repeat it to merge overlays over it, in order").ExistingFiles()
+	clusterInitInteractiveFlag = clusterInitCommand.Flag("interactive", "Ask for the settings of the cluster and write its config, instead of reading --config").Bool()
+	clusterInitAnswersFlag     = clusterInitCommand.Flag("answers", "With --interactive, read the answers from a YAML file mapping the questions to their answers").ExistingFile()
+	clusterInitWriteFlag       = clusterInitCommand.Flag("write-config", "With --interactive, the path the config is written at").Default("config.yaml").String()
 
 	clusterInstallCommand          = kingpin.Command("install", "Create a new Tectonic cluster")
 	clusterInstallTLSCommand       = clusterInstallCommand.Command("tls", "Generate TLS Certificates.")
@@ -19,26 +26,80 @@ var (
 	clusterInstallAssetsCommand    = clusterInstallCommand.Command("assets", "Generate Tectonic assets.")
 	clusterInstallBootstrapCommand = clusterInstallCommand.Command("bootstrap", "Create a single bootstrap node Tectonic cluster.")
 	clusterInstallFullCommand      = clusterInstallCommand.Command("full", "Create a new Tectonic cluster").Default()
+	clusterInstallFromStepFlag     = clusterInstallFullCommand.Flag("from-step", "Skip the steps before the named step, e.g. \"etcd\"").String()
+	clusterInstallUntilStepFlag    = clusterInstallFullCommand.Flag("until-step", "Stop after the named step, e.g. \"topology\"").String()
 	clusterInstallJoinCommand      = clusterInstallCommand.Command("join", "Create master and worker nodes to join an exisiting Tectonic cluster.")
+	clusterInstallResumeCommand    = clusterInstallCommand.Command("resume", "Resume an interrupted install at its first incomplete step.")
+	clusterInstallPlanCommand      = clusterInstallCommand.Command("plan", "Show the changes Terraform would make in every step of an install.")
+	clusterInstallPlanJoinFlag     = clusterInstallPlanCommand.Flag("join", "Plan the steps of the join workflow instead of a full install").Bool()
 	clusterInstallDirFlag          = clusterInstallCommand.Flag("dir", "Cluster directory").Default(".").ExistingDir()
 
-	clusterDestroyCommand = kingpin.Command("destroy", "Destroy an existing Tectonic cluster")
-	clusterDestroyDirFlag = clusterDestroyCommand.Flag("dir", "Cluster directory").Default(".").ExistingDir()
+	clusterDestroyCommand       = kingpin.Command("destroy", "Destroy an existing Tectonic cluster")
+	clusterDestroyDirFlag       = clusterDestroyCommand.Flag("dir", "Cluster directory").Default(".").ExistingDir()
+	clusterDestroyFromStepFlag  = clusterDestroyCommand.Flag("from-step", "Skip the steps before the named step, e.g. \"topology\"").String()
+	clusterDestroyUntilStepFlag = clusterDestroyCommand.Flag("until-step", "Stop after the named step, e.g. \"etcd\"").String()
+	clusterDestroyContinueFlag  = clusterDestroyCommand.Flag("continue-on-error", "Attempt every step even if some fail, retry the failed ones and report the resources left").Bool()
+	clusterDestroyDryRunFlag    = clusterDestroyCommand.Flag("dry-run", "List the resources every step would destroy, without destroying them").Bool()
+
+	clusterScaleCommand = kingpin.Command("scale", "Scale the master and worker nodes of a Tectonic cluster to the counts of their node pools")
+	clusterScaleDirFlag = clusterScaleCommand.Flag("dir", "Cluster directory").Default(".").ExistingDir()
+
+	clusterStatusCommand = kingpin.Command("status", "Report the lifecycle state of a Tectonic cluster")
+	clusterStatusDirFlag = clusterStatusCommand.Flag("dir", "Cluster directory").Default(".").ExistingDir()
+
+	configCommand            = kingpin.Command("config", "Inspect and maintain the config of a Tectonic cluster")
+	configDiffCommand        = configCommand.Command("diff", "Show the changes of the config since it was last applied")
+	configDiffDirFlag        = configDiffCommand.Flag("dir", "Cluster directory").Default(".").ExistingDir()
+	configMigrateCommand     = configCommand.Command("migrate", "Upgrade a config to the current apiVersion in place, backing up the original")
+	configMigrateConfigFlag  = configMigrateCommand.Flag("config", "Cluster specification file").Required().ExistingFile()
+	configValidateCommand    = configCommand.Command("validate", "Report the errors and warnings of a config, with the path and line of their fields")
+	configValidateConfigFlag = configValidateCommand.Flag("config", "Cluster specification file").Required().ExistingFile()
+	configSchemaCommand      = configCommand.Command("schema", "Print the JSON Schema of the config, for editors to complete and check configs")
+	configRenderCommand      = configCommand.Command("render", "Print the effective config, with the defaults of its platform and its derived values")
+	configRenderDirFlag      = configRenderCommand.Flag("dir", "Cluster directory, whose config is rendered unless --config is set").Default(".").ExistingDir()
+	configRenderConfigFlag   = configRenderCommand.Flag("config", "Cluster specification file; repeat it to merge overlays over it, in order").ExistingFiles()
+	configRenderFormatFlag   = configRenderCommand.Flag("format", "Rendering format; \"tfvars\" prints the terraform.tfvars JSON applied by the install").Default("yaml").Enum("yaml", "tfvars")
+	configRenderSecretsFlag  = configRenderCommand.Flag("show-secrets", "Print the admin password instead of hiding it").Bool()
 
 	convertCommand    = kingpin.Command("convert", "Convert a tfvars.json to a Tectonic config.yaml")
 	convertConfigFlag = convertCommand.Flag("config", "tfvars.json file").Required().ExistingFile()
+	convertWriteFlag  = convertCommand.Flag("write-config", "Write the config at the given path instead of printing it").String()
 
-	logLevel = kingpin.Flag("log-level", "log level (e.g. \"debug\")").Default("info").Enum("debug", "info", "warn", "error", "fatal", "panic")
+	migrateStateCommand    = kingpin.Command("migrate-state", "Move a cluster installed with the legacy single state into a cluster directory with a state per step")
+	migrateStateTFVarsFlag = migrateStateCommand.Flag("tfvars", "tfvars.json file of the legacy install").Required().ExistingFile()
+	migrateStateStateFlag  = migrateStateCommand.Flag("state", "State file of the legacy install, left untouched").Required().ExistingFile()
+
+	forceUnlock  = kingpin.Flag("force-unlock", "remove a stale lock of the cluster directory before running").Bool()
+	outputFormat = kingpin.Flag("output", "output format; \"json\" emits the workflow progress as newline-delimited JSON events").Default("text").Enum("text", "json")
+	logLevel     = kingpin.Flag("log-level", "log level (e.g. \"debug\")").Default("info").Enum("debug", "info", "warn", "error", "fatal", "panic")
 )
 
 func main() {
 	var w workflow.Workflow
 
-	switch kingpin.Parse() {
+	command := kingpin.Parse()
+	switch command {
 	case clusterInitCommand.FullCommand():
-		w = workflow.InitWorkflow(*clusterInitConfigFlag)
+		switch {
+		case *clusterInitInteractiveFlag:
+			wiz := wizard.New(os.Stdin, os.Stdout)
+			if *clusterInitAnswersFlag != "" {
+				var err error
+				if wiz, err = wizard.NewFromAnswersFile(*clusterInitAnswersFlag); err != nil {
+					log.Fatal(err)
+				}
+			}
+			w = workflow.InitWizardWorkflow(*clusterInitWriteFlag, wiz)
+		case len(*clusterInitConfigFlag) == 0:
+			log.Fatal("--config is required, unless --interactive is set")
+		default:
+			w = workflow.InitWorkflow((*clusterInitConfigFlag)[0], (*clusterInitConfigFlag)[1:]...)
+		}
 	case clusterInstallFullCommand.FullCommand():
-		w = workflow.InstallFullWorkflow(*clusterInstallDirFlag)
+		var err error
+		if w, err = workflow.InstallFullWorkflow(*clusterInstallDirFlag).WithSteps(*clusterInstallFromStepFlag, *clusterInstallUntilStepFlag); err != nil {
+			log.Fatal(err)
+		}
 	case clusterInstallTLSCommand.FullCommand():
 		w = workflow.InstallTLSWorkflow(*clusterInstallDirFlag)
 	case clusterInstallTLSNewCommand.FullCommand():
@@ -49,10 +110,44 @@ func main() {
 		w = workflow.InstallBootstrapWorkflow(*clusterInstallDirFlag)
 	case clusterInstallJoinCommand.FullCommand():
 		w = workflow.InstallJoinWorkflow(*clusterInstallDirFlag)
+	case clusterInstallResumeCommand.FullCommand():
+		var err error
+		if w, err = workflow.ResumeWorkflow(*clusterInstallDirFlag); err != nil {
+			log.Fatal(err)
+		}
+	case clusterInstallPlanCommand.FullCommand():
+		if *clusterInstallPlanJoinFlag {
+			w = workflow.PlanJoinWorkflow(*clusterInstallDirFlag)
+		} else {
+			w = workflow.PlanFullWorkflow(*clusterInstallDirFlag)
+		}
 	case clusterDestroyCommand.FullCommand():
-		w = workflow.DestroyWorkflow(*clusterDestroyDirFlag)
+		w = workflow.DestroyWorkflow(*clusterDestroyDirFlag, *clusterDestroyContinueFlag)
+		if *clusterDestroyDryRunFlag {
+			w = workflow.DestroyDryRunWorkflow(*clusterDestroyDirFlag)
+		}
+		var err error
+		if w, err = w.WithSteps(*clusterDestroyFromStepFlag, *clusterDestroyUntilStepFlag); err != nil {
+			log.Fatal(err)
+		}
+	case clusterScaleCommand.FullCommand():
+		w = workflow.ScaleWorkflow(*clusterScaleDirFlag)
+	case clusterStatusCommand.FullCommand():
+		w = workflow.StatusWorkflow(*clusterStatusDirFlag, *outputFormat == "json")
+	case configDiffCommand.FullCommand():
+		w = workflow.ConfigDiffWorkflow(*configDiffDirFlag)
+	case configMigrateCommand.FullCommand():
+		w = workflow.ConfigMigrateWorkflow(*configMigrateConfigFlag)
+	case configValidateCommand.FullCommand():
+		w = workflow.ConfigValidateWorkflow(*configValidateConfigFlag, *outputFormat == "json")
+	case configSchemaCommand.FullCommand():
+		w = workflow.ConfigSchemaWorkflow()
+	case configRenderCommand.FullCommand():
+		w = workflow.ConfigRenderWorkflow(*configRenderDirFlag, *configRenderConfigFlag, *configRenderFormatFlag == "tfvars", *configRenderSecretsFlag)
 	case convertCommand.FullCommand():
-		w = workflow.ConvertWorkflow(*convertConfigFlag)
+		w = workflow.ConvertWorkflow(*convertConfigFlag, *convertWriteFlag)
+	case migrateStateCommand.FullCommand():
+		w = workflow.MigrateStateWorkflow(*migrateStateTFVarsFlag, *migrateStateStateFlag)
 	}
 
 	l, err := log.ParseLevel(*logLevel)
@@ -62,8 +157,46 @@ func main() {
 	}
 	log.SetLevel(l)
 
-	if err := w.Execute(); err != nil {
+	if *forceUnlock {
+		w = w.WithForceUnlock()
+	}
+
+	if *outputFormat == "json" {
+		log.SetFormatter(&log.JSONFormatter{})
+		// The status and validation reports are printed as JSON instead of
+		// emitting events, and the schema and rendered configs are printed
+		// as is.
+		switch command {
+		case clusterStatusCommand.FullCommand(), configValidateCommand.FullCommand(), configSchemaCommand.FullCommand(), configRenderCommand.FullCommand():
+		default:
+			w = w.WithEvents(os.Stdout)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx, kill := workflow.WithKill(ctx)
+	defer kill()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go handleSignals(signals, cancel, kill)
+
+	if err := w.Execute(ctx); err != nil {
 		log.Fatal(err)
 		os.Exit(1)
 	}
 }
+
+// handleSignals cancels the running workflow on the first SIGINT or SIGTERM,
+// letting the current step stop gracefully. The second one kills TerraForm
+// instead, the workflow still releasing the lock of the cluster directory
+// and journaling the step as interrupted before exiting.
+func handleSignals(signals <-chan os.Signal, cancel, kill context.CancelFunc) {
+	sig := <-signals
+	log.Warnf("Received %s, waiting for the current step to stop. Send it again to stop it immediately.", sig)
+	cancel()
+
+	sig = <-signals
+	log.Warnf("Received %s, killing TerraForm", sig)
+	kill()
+}
@@ -17,7 +17,6 @@ var (
 	ignVersion   = "2.2.0"
 	ignFilesPath = map[string]string{
 		"master": config.IgnitionMaster,
-		"worker": config.IgnitionWorker,
 		"etcd":   config.IgnitionEtcd,
 	}
 	caPath = "generated/tls/root-ca.crt"
@@ -39,6 +38,7 @@ func (c *ConfigGenerator) poolToRoleMap() map[string]string {
 }
 
 // GenerateIgnConfig generates, if successful, files with the ign config for each role.
+// Every worker pool gets a file of its own.
 func (c *ConfigGenerator) GenerateIgnConfig(clusterDir string) error {
 	poolToRole := c.poolToRoleMap()
 	for _, p := range c.NodePools {
@@ -61,6 +61,9 @@ func (c *ConfigGenerator) GenerateIgnConfig(clusterDir string) error {
 		c.embedUserBlock(ignCfg)
 
 		fileTargetPath := filepath.Join(clusterDir, ignFilesPath[role])
+		if role == "worker" {
+			fileTargetPath = filepath.Join(clusterDir, config.WorkerPoolIgnition(p.Name))
+		}
 		if err = ignCfgToFile(*ignCfg, fileTargetPath); err != nil {
 			return err
 		}
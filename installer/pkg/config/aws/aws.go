@@ -90,3 +90,19 @@ type WorkerRootVolume struct {
 	Size int    `json:"tectonic_aws_worker_root_volume_size,omitempty" yaml:"size,omitempty"`
 	Type string `json:"tectonic_aws_worker_root_volume_type,omitempty" yaml:"type,omitempty"`
 }
+
+// NodePool converts the AWS settings of a node pool. They override the
+// settings of the role of the pool, which apply to the unset ones.
+type NodePool struct {
+	EC2Type     string     `json:"ec2_type,omitempty" yaml:"ec2Type,omitempty"`
+	ExtraSGIDs  []string   `json:"extra_sg_ids,omitempty" yaml:"extraSGIDs,omitempty"`
+	IAMRoleName string     `json:"iam_role_name,omitempty" yaml:"iamRoleName,omitempty"`
+	RootVolume  RootVolume `json:"root_volume,omitempty" yaml:"rootVolume,omitempty"`
+}
+
+// RootVolume converts the root volume related config of a node pool.
+type RootVolume struct {
+	IOPS int    `json:"iops,omitempty" yaml:"iops,omitempty"`
+	Size int    `json:"size,omitempty" yaml:"size,omitempty"`
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+}
@@ -3,6 +3,9 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/coreos/tectonic-config/config/tectonic-network"
 	"gopkg.in/yaml.v2"
@@ -14,16 +17,26 @@ import (
 const (
 	// IgnitionMaster is the relative path to the ign master cfg from the tf working directory
 	IgnitionMaster = "ignition-master.ign"
-	// IgnitionWorker is the relative path to the ign worker cfg from the tf working directory
-	IgnitionWorker = "ignition-worker.ign"
 	// IgnitionEtcd is the relative path to the ign etcd cfg from the tf working directory
 	IgnitionEtcd = "ignition-etcd.ign"
+	// IgnitionWorker is the relative path to the ign cfg of the default
+	// worker pool, named worker, from the tf working directory.
+	//
+	// Deprecated: every worker pool has an ign cfg of its own, use
+	// WorkerPoolIgnition.
+	IgnitionWorker = "ignition-worker-worker.ign"
 	// PlatformAWS is the platform for a cluster launched on AWS.
 	PlatformAWS Platform = "aws"
 	// PlatformLibvirt is the platform for a cluster launched on libvirt.
 	PlatformLibvirt Platform = "libvirt"
 )
 
+// WorkerPoolIgnition returns the relative path to the ign cfg of the worker
+// pool of the given name from the tf working directory.
+func WorkerPoolIgnition(pool string) string {
+	return fmt.Sprintf("ignition-worker-%s.ign", pool)
+}
+
 // Platform indicates the target platform of the cluster.
 type Platform string
 
@@ -45,13 +58,12 @@ func (p *Platform) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	return nil
 }
 
+// defaultCluster holds the defaults of the settings common to every
+// platform. The defaults of the platform settings are only applied for the
+// selected platform, see applyPlatformDefaults.
 var defaultCluster = Cluster{
-	AWS: aws.AWS{
-		Endpoints:    aws.EndpointsAll,
-		Profile:      aws.DefaultProfile,
-		Region:       aws.DefaultRegion,
-		VPCCIDRBlock: aws.DefaultVPCCIDRBlock,
-	},
+	APIVersion: APIVersion,
+	Kind:       Kind,
 	CA: CA{
 		RootCAKeyAlg: "RSA",
 	},
@@ -59,12 +71,6 @@ var defaultCluster = Cluster{
 		Channel: ContainerLinuxChannelStable,
 		Version: ContainerLinuxVersionLatest,
 	},
-	Libvirt: libvirt.Libvirt{
-		Network: libvirt.Network{
-			DNSServer: libvirt.DefaultDNSServer,
-			IfName:    libvirt.DefaultIfName,
-		},
-	},
 	Networking: Networking{
 		MTU:         "1480",
 		PodCIDR:     "10.2.0.0/16",
@@ -73,8 +79,36 @@ var defaultCluster = Cluster{
 	},
 }
 
+// applyPlatformDefaults sets the unset settings of the selected platform to
+// their defaults.
+func (c *Cluster) applyPlatformDefaults() {
+	switch c.Platform {
+	case PlatformAWS:
+		setDefault(&c.AWS.Endpoints, aws.EndpointsAll)
+		setDefault(&c.AWS.Profile, aws.DefaultProfile)
+		setDefault(&c.AWS.Region, aws.DefaultRegion)
+		setDefault(&c.AWS.VPCCIDRBlock, aws.DefaultVPCCIDRBlock)
+	case PlatformLibvirt:
+		setDefault(&c.Libvirt.Network.DNSServer, libvirt.DefaultDNSServer)
+		setDefault(&c.Libvirt.Network.IfName, libvirt.DefaultIfName)
+	}
+}
+
+// setDefault sets the string setting at the given pointer, a string or a
+// type based on string, to the given default if it is empty.
+func setDefault(setting, def interface{}) {
+	v := reflect.ValueOf(setting).Elem()
+	if v.String() == "" {
+		v.SetString(reflect.ValueOf(def).String())
+	}
+}
+
 // Cluster defines the config for a cluster.
 type Cluster struct {
+	// APIVersion and Kind identify the schema of the config. They come
+	// first so that they head the YAML representation of the config.
+	APIVersion      string `json:"-" yaml:"apiVersion,omitempty"`
+	Kind            string `json:"-" yaml:"kind,omitempty"`
 	Admin           `json:",inline" yaml:"admin,omitempty"`
 	aws.AWS         `json:",inline" yaml:"aws,omitempty"`
 	BaseDomain      string `json:"tectonic_base_domain,omitempty" yaml:"baseDomain,omitempty"`
@@ -83,16 +117,17 @@ type Cluster struct {
 	Etcd            `json:",inline" yaml:"etcd,omitempty"`
 	IgnitionEtcd    string `json:"tectonic_ignition_etcd,omitempty" yaml:"-"`
 	IgnitionMaster  string `json:"tectonic_ignition_master,omitempty" yaml:"-"`
-	IgnitionWorker  string `json:"tectonic_ignition_worker,omitempty" yaml:"-"`
 	Internal        `json:",inline" yaml:"-"`
 	libvirt.Libvirt `json:",inline" yaml:"libvirt,omitempty"`
-	LicensePath     string `json:"tectonic_license_path,omitempty" yaml:"licensePath,omitempty"`
+	LicensePath     string     `json:"tectonic_license_path,omitempty" yaml:"licensePath,omitempty"`
+	LicenseFrom     *SecretRef `json:"-" yaml:"licenseFrom,omitempty"`
 	Master          `json:",inline" yaml:"master,omitempty"`
 	Name            string `json:"tectonic_cluster_name,omitempty" yaml:"name,omitempty"`
 	Networking      `json:",inline" yaml:"networking,omitempty"`
 	NodePools       `json:"-" yaml:"nodePools"`
-	Platform        Platform `json:"tectonic_platform" yaml:"platform,omitempty"`
-	PullSecretPath  string   `json:"tectonic_pull_secret_path,omitempty" yaml:"pullSecretPath,omitempty"`
+	Platform        Platform   `json:"tectonic_platform" yaml:"platform,omitempty"`
+	PullSecretPath  string     `json:"tectonic_pull_secret_path,omitempty" yaml:"pullSecretPath,omitempty"`
+	PullSecretFrom  *SecretRef `json:"-" yaml:"pullSecretFrom,omitempty"`
 	Worker          `json:",inline" yaml:"worker,omitempty"`
 }
 
@@ -111,24 +146,216 @@ func (c Cluster) NodeCount(names []string) int {
 	return count
 }
 
-// TFVars will return the config for the cluster in tfvars format.
-func (c *Cluster) TFVars() (string, error) {
+// Derive fills in the values derived from the config: the node counts of
+// the roles, the paths of the ignition configs and the libvirt master IPs.
+func (c *Cluster) Derive() error {
 	c.Etcd.Count = c.NodeCount(c.Etcd.NodePools)
 	c.Master.Count = c.NodeCount(c.Master.NodePools)
 	c.Worker.Count = c.NodeCount(c.Worker.NodePools)
 
 	c.IgnitionMaster = IgnitionMaster
-	c.IgnitionWorker = IgnitionWorker
 	c.IgnitionEtcd = IgnitionEtcd
 
 	// fill in master ips
 	if c.Platform == PlatformLibvirt {
-		if err := c.Libvirt.TFVars(c.Master.Count); err != nil {
-			return "", err
+		return c.Libvirt.TFVars(c.Master.Count)
+	}
+	return nil
+}
+
+// roleSettings points to the platform settings of the nodes of a role.
+type roleSettings struct {
+	nodePools   []string
+	ec2Type     *string
+	extraSGIDs  *[]string
+	iamRoleName *string
+	iops        *int
+	size        *int
+	volumeType  *string
+	memory      *int
+	vcpu        *int
+}
+
+// roles returns the platform settings of the etcd, master and worker roles.
+func (c *Cluster) roles() []roleSettings {
+	return []roleSettings{
+		{
+			nodePools:   c.Etcd.NodePools,
+			ec2Type:     &c.AWS.Etcd.EC2Type,
+			extraSGIDs:  &c.AWS.Etcd.ExtraSGIDs,
+			iamRoleName: &c.AWS.Etcd.IAMRoleName,
+			iops:        &c.AWS.Etcd.EtcdRootVolume.IOPS,
+			size:        &c.AWS.Etcd.EtcdRootVolume.Size,
+			volumeType:  &c.AWS.Etcd.EtcdRootVolume.Type,
+			memory:      &c.Libvirt.EtcdMemory,
+			vcpu:        &c.Libvirt.EtcdVCPU,
+		},
+		{
+			nodePools:   c.Master.NodePools,
+			ec2Type:     &c.AWS.Master.EC2Type,
+			extraSGIDs:  &c.AWS.Master.ExtraSGIDs,
+			iamRoleName: &c.AWS.Master.IAMRoleName,
+			iops:        &c.AWS.Master.MasterRootVolume.IOPS,
+			size:        &c.AWS.Master.MasterRootVolume.Size,
+			volumeType:  &c.AWS.Master.MasterRootVolume.Type,
+			memory:      &c.Libvirt.MasterMemory,
+			vcpu:        &c.Libvirt.MasterVCPU,
+		},
+		{
+			nodePools:   c.Worker.NodePools,
+			ec2Type:     &c.AWS.Worker.EC2Type,
+			extraSGIDs:  &c.AWS.Worker.ExtraSGIDs,
+			iamRoleName: &c.AWS.Worker.IAMRoleName,
+			iops:        &c.AWS.Worker.WorkerRootVolume.IOPS,
+			size:        &c.AWS.Worker.WorkerRootVolume.Size,
+			volumeType:  &c.AWS.Worker.WorkerRootVolume.Type,
+			memory:      &c.Libvirt.WorkerMemory,
+			vcpu:        &c.Libvirt.WorkerVCPU,
+		},
+	}
+}
+
+// applyNodePoolSettings sets the platform settings of every role with a
+// single node pool to the settings of the pool, when the pool sets them.
+// The settings of several worker pools go in the worker pools variable.
+func (c *Cluster) applyNodePoolSettings() {
+	for _, r := range c.roles() {
+		if len(r.nodePools) != 1 {
+			continue
+		}
+		pool := c.NodePools.NodePool(r.nodePools[0])
+		if pool == nil {
+			continue
+		}
+		if p := pool.AWS; p != nil {
+			overrideString(r.ec2Type, p.EC2Type)
+			overrideString(r.iamRoleName, p.IAMRoleName)
+			overrideString(r.volumeType, p.RootVolume.Type)
+			overrideInt(r.iops, p.RootVolume.IOPS)
+			overrideInt(r.size, p.RootVolume.Size)
+			if len(p.ExtraSGIDs) > 0 {
+				*r.extraSGIDs = p.ExtraSGIDs
+			}
+		}
+		if p := pool.Libvirt; p != nil {
+			overrideInt(r.memory, p.Memory)
+			overrideInt(r.vcpu, p.VCPU)
+		}
+	}
+}
+
+// overrideString sets the setting to the given value, unless it is empty.
+func overrideString(setting *string, value string) {
+	if value != "" {
+		*setting = value
+	}
+}
+
+// overrideInt sets the setting to the given value, unless it is zero.
+func overrideInt(setting *int, value int) {
+	if value != 0 {
+		*setting = value
+	}
+}
+
+// workerPools returns the worker pools of the tfvars, in the order of the
+// node pools of the worker role.
+func (c *Cluster) workerPools() []WorkerPool {
+	var pools []WorkerPool
+	for _, name := range c.Worker.NodePools {
+		n := c.NodePools.NodePool(name)
+		if n == nil {
+			continue
 		}
+		p := WorkerPool{
+			Name:     n.Name,
+			Count:    strconv.Itoa(n.Count),
+			Ignition: WorkerPoolIgnition(n.Name),
+		}
+		if a := n.AWS; a != nil {
+			p.EC2Type = a.EC2Type
+			p.ExtraSGIDs = strings.Join(a.ExtraSGIDs, ",")
+			p.IAMRoleName = a.IAMRoleName
+			p.RootVolumeIOPS = itoa(a.RootVolume.IOPS)
+			p.RootVolumeSize = itoa(a.RootVolume.Size)
+			p.RootVolumeType = a.RootVolume.Type
+		}
+		if l := n.Libvirt; l != nil {
+			p.Memory = itoa(l.Memory)
+			p.VCPU = itoa(l.VCPU)
+		}
+		pools = append(pools, p)
+	}
+	return pools
+}
+
+// libvirtWorkerNodes returns the name of every worker domain, by index, and
+// the index of its pool in the given worker pools. TerraForm identifies the
+// domains by index, so the domains recorded by the last apply keep theirs
+// and the new ones follow: scaling up a pool does not replace the domains
+// of the other pools.
+func (c *Cluster) libvirtWorkerNodes(pools []WorkerPool) ([]string, []string) {
+	poolIndex := make(map[string]int)
+	var configured []string
+	for i, p := range pools {
+		count, _ := strconv.Atoi(p.Count)
+		for n := 0; n < count; n++ {
+			name := libvirtWorkerName(p.Name, n)
+			poolIndex[name] = i
+			configured = append(configured, name)
+		}
+	}
+
+	var names, nodes []string
+	placed := make(map[string]bool)
+	for _, name := range append(append([]string{}, c.Libvirt.AppliedWorkerNodes...), configured...) {
+		i, ok := poolIndex[name]
+		if !ok || placed[name] {
+			continue
+		}
+		placed[name] = true
+		names = append(names, name)
+		nodes = append(nodes, strconv.Itoa(i))
+	}
+	return names, nodes
+}
+
+// libvirtWorkerName returns the name of the given domain of a worker pool.
+// The pool named worker keeps the names of the clusters installed before
+// worker pools.
+func libvirtWorkerName(pool string, i int) string {
+	if pool == "worker" {
+		return fmt.Sprintf("worker%d", i)
 	}
+	return fmt.Sprintf("worker-%s-%d", pool, i)
+}
+
+// itoa returns the given setting as a string, or "" if it is unset.
+func itoa(i int) string {
+	if i == 0 {
+		return ""
+	}
+	return strconv.Itoa(i)
+}
 
-	data, err := json.MarshalIndent(&c, "", "  ")
+// TFVars will return the config for the cluster in tfvars format.
+// The platform settings of the node pools override those of their roles,
+// and every worker pool is a variable of its own.
+func (c *Cluster) TFVars() (string, error) {
+	if err := c.Derive(); err != nil {
+		return "", err
+	}
+
+	// The settings of the node pools only apply to the variables, keeping
+	// the config as written.
+	vars := *c
+	vars.Worker.Pools = vars.workerPools()
+	if vars.Platform == PlatformLibvirt {
+		vars.Libvirt.WorkerNodes, vars.Libvirt.WorkerPoolNodes = vars.libvirtWorkerNodes(vars.Worker.Pools)
+	}
+	vars.applyNodePoolSettings()
+
+	data, err := json.MarshalIndent(&vars, "", "  ")
 	if err != nil {
 		return "", err
 	}
@@ -137,24 +364,36 @@ func (c *Cluster) TFVars() (string, error) {
 }
 
 // YAML will return the config for the cluster in yaml format.
+// The roles without node pools get a node pool of their name, holding the
+// node count and the libvirt memory and vCPUs of the role.
 func (c *Cluster) YAML() (string, error) {
-	c.NodePools = append(c.NodePools, NodePool{
-		Count: c.Etcd.Count,
-		Name:  "etcd",
-	})
-	c.Etcd.NodePools = []string{"etcd"}
-
-	c.NodePools = append(c.NodePools, NodePool{
-		Count: c.Master.Count,
-		Name:  "master",
-	})
-	c.Master.NodePools = []string{"master"}
-
-	c.NodePools = append(c.NodePools, NodePool{
-		Count: c.Worker.Count,
-		Name:  "worker",
-	})
-	c.Worker.NodePools = []string{"worker"}
+	for _, r := range []struct {
+		name      string
+		count     int
+		nodePools *[]string
+		memory    int
+		vcpu      int
+	}{
+		{name: "etcd", count: c.Etcd.Count, nodePools: &c.Etcd.NodePools, memory: c.Libvirt.EtcdMemory, vcpu: c.Libvirt.EtcdVCPU},
+		{name: "master", count: c.Master.Count, nodePools: &c.Master.NodePools, memory: c.Libvirt.MasterMemory, vcpu: c.Libvirt.MasterVCPU},
+		{name: "worker", count: c.Worker.Count, nodePools: &c.Worker.NodePools, memory: c.Libvirt.WorkerMemory, vcpu: c.Libvirt.WorkerVCPU},
+	} {
+		if len(*r.nodePools) > 0 {
+			continue
+		}
+		pool := NodePool{
+			Count: r.count,
+			Name:  r.name,
+		}
+		if r.memory != 0 || r.vcpu != 0 {
+			pool.Libvirt = &libvirt.NodePool{Memory: r.memory, VCPU: r.vcpu}
+		}
+		c.NodePools = append(c.NodePools, pool)
+		*r.nodePools = []string{r.name}
+	}
+
+	c.APIVersion = APIVersion
+	c.Kind = Kind
 
 	yaml, err := yaml.Marshal(c)
 	if err != nil {
@@ -0,0 +1,238 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config/aws"
+	"github.com/coreos/tectonic-installer/installer/pkg/config/libvirt"
+)
+
+func TestParseConfigPlatformDefaults(t *testing.T) {
+	cases := []struct {
+		name            string
+		data            string
+		expectedAWS     aws.AWS
+		expectedLibvirt libvirt.Libvirt
+	}{
+		{
+			name: "aws",
+			data: "platform: aws\naws:\n  region: us-east-1\n",
+			expectedAWS: aws.AWS{
+				Endpoints:    aws.EndpointsAll,
+				Profile:      aws.DefaultProfile,
+				Region:       "us-east-1",
+				VPCCIDRBlock: aws.DefaultVPCCIDRBlock,
+			},
+		},
+		{
+			name: "libvirt",
+			data: "platform: libvirt\nlibvirt:\n  network:\n    ifName: tt0\n",
+			expectedLibvirt: libvirt.Libvirt{
+				Network: libvirt.Network{
+					DNSServer: libvirt.DefaultDNSServer,
+					IfName:    "tt0",
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		cluster, err := ParseConfig([]byte(c.data))
+		if err != nil {
+			t.Errorf("test case %s: failed to parse config: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(cluster.AWS, c.expectedAWS) {
+			t.Errorf("test case %s: expected AWS settings %+v, got %+v", c.name, c.expectedAWS, cluster.AWS)
+		}
+		if !reflect.DeepEqual(cluster.Libvirt, c.expectedLibvirt) {
+			t.Errorf("test case %s: expected libvirt settings %+v, got %+v", c.name, c.expectedLibvirt, cluster.Libvirt)
+		}
+	}
+}
+
+func TestDerive(t *testing.T) {
+	cluster, err := ParseConfig([]byte(`platform: libvirt
+libvirt:
+  network:
+    ipRange: 192.168.124.0/24
+etcd:
+  nodePools: [etcd]
+master:
+  nodePools: [master]
+worker:
+  nodePools: [worker, gpu]
+nodePools:
+  - name: etcd
+    count: 1
+  - name: master
+    count: 2
+  - name: worker
+    count: 3
+  - name: gpu
+    count: 1
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	if err := cluster.Derive(); err != nil {
+		t.Fatalf("failed to derive the config values: %v", err)
+	}
+
+	if cluster.Etcd.Count != 1 || cluster.Master.Count != 2 || cluster.Worker.Count != 4 {
+		t.Errorf("expected node counts 1, 2 and 4, got %d, %d and %d", cluster.Etcd.Count, cluster.Master.Count, cluster.Worker.Count)
+	}
+	if expected := []string{"192.168.124.10", "192.168.124.11"}; !reflect.DeepEqual(cluster.Libvirt.MasterIPs, expected) {
+		t.Errorf("expected master IPs %v, got %v", expected, cluster.Libvirt.MasterIPs)
+	}
+	if cluster.IgnitionMaster != IgnitionMaster {
+		t.Errorf("expected the master ignition path %q, got %q", IgnitionMaster, cluster.IgnitionMaster)
+	}
+}
+
+func TestTFVarsNodePoolSettings(t *testing.T) {
+	cluster, err := ParseConfig([]byte(`platform: aws
+aws:
+  master:
+    ec2Type: m4.large
+  worker:
+    ec2Type: m4.large
+    rootVolume:
+      size: 30
+      type: gp2
+etcd:
+  nodePools: [etcd]
+master:
+  nodePools: [master]
+worker:
+  nodePools: [high-memory]
+nodePools:
+  - name: etcd
+    count: 1
+  - name: master
+    count: 1
+  - name: high-memory
+    count: 2
+    aws:
+      ec2Type: r4.xlarge
+      extraSGIDs: [sg-1]
+      rootVolume:
+        size: 100
+`))
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+	data, err := cluster.TFVars()
+	if err != nil {
+		t.Fatalf("failed to generate tfvars: %v", err)
+	}
+	var vars map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &vars); err != nil {
+		t.Fatalf("failed to unmarshal tfvars: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"tectonic_aws_master_ec2_type":         "m4.large",
+		"tectonic_aws_worker_ec2_type":         "r4.xlarge",
+		"tectonic_aws_worker_extra_sg_ids":     []interface{}{"sg-1"},
+		"tectonic_aws_worker_root_volume_size": float64(100),
+		"tectonic_aws_worker_root_volume_type": "gp2",
+	}
+	for name, value := range expected {
+		if !reflect.DeepEqual(vars[name], value) {
+			t.Errorf("expected %s to be %v, got %v", name, value, vars[name])
+		}
+	}
+	// The config keeps the settings of the roles as written.
+	if cluster.AWS.Worker.EC2Type != "m4.large" {
+		t.Errorf("expected the worker role settings to be unchanged, got the EC2 type %q", cluster.AWS.Worker.EC2Type)
+	}
+}
+
+func TestTFVarsWorkerPools(t *testing.T) {
+	cases := []struct {
+		platform string
+		config   string
+		gpu      string
+		pools    string
+		names    []interface{}
+		nodes    []interface{}
+	}{
+		{
+			platform: "aws",
+			gpu:      "aws: {ec2Type: p2.xlarge, extraSGIDs: [sg-1, sg-2]}",
+			pools:    `[{"name":"worker","count":"2","ignition":"ignition-worker-worker.ign"},{"name":"gpu","count":"1","ignition":"ignition-worker-gpu.ign","ec2_type":"p2.xlarge","extra_sg_ids":"sg-1,sg-2"}]`,
+		},
+		{
+			platform: "libvirt",
+			config:   "libvirt: {network: {ipRange: 192.168.124.0/24}}",
+			gpu:      "libvirt: {memory: 4096}",
+			pools:    `[{"name":"worker","count":"2","ignition":"ignition-worker-worker.ign"},{"name":"gpu","count":"1","ignition":"ignition-worker-gpu.ign","memory":"4096"}]`,
+			names:    []interface{}{"worker0", "worker1", "worker-gpu-0"},
+			nodes:    []interface{}{"0", "0", "1"},
+		},
+	}
+
+	for _, c := range cases {
+		cluster, err := ParseConfig([]byte(fmt.Sprintf(`platform: %s
+%s
+etcd:
+  nodePools: [etcd]
+master:
+  nodePools: [master]
+worker:
+  nodePools: [worker, gpu]
+nodePools:
+  - name: etcd
+    count: 1
+  - name: master
+    count: 1
+  - name: worker
+    count: 2
+  - name: gpu
+    count: 1
+    %s
+`, c.platform, c.config, c.gpu)))
+		if err != nil {
+			t.Fatalf("test case %s: failed to parse config: %v", c.platform, err)
+		}
+		data, err := cluster.TFVars()
+		if err != nil {
+			t.Fatalf("test case %s: failed to generate tfvars: %v", c.platform, err)
+		}
+		var vars map[string]interface{}
+		if err := json.Unmarshal([]byte(data), &vars); err != nil {
+			t.Fatalf("test case %s: failed to unmarshal tfvars: %v", c.platform, err)
+		}
+
+		var pools interface{}
+		if err := json.Unmarshal([]byte(c.pools), &pools); err != nil {
+			t.Fatalf("test case %s: failed to unmarshal the expected pools: %v", c.platform, err)
+		}
+		if !reflect.DeepEqual(vars["tectonic_worker_pools"], pools) {
+			t.Errorf("test case %s: expected the worker pools %v, got %v", c.platform, pools, vars["tectonic_worker_pools"])
+		}
+		if names, _ := vars["tectonic_libvirt_worker_nodes"].([]interface{}); !reflect.DeepEqual(names, c.names) {
+			t.Errorf("test case %s: expected the worker nodes %v, got %v", c.platform, c.names, names)
+		}
+		if nodes, _ := vars["tectonic_libvirt_worker_pool_nodes"].([]interface{}); !reflect.DeepEqual(nodes, c.nodes) {
+			t.Errorf("test case %s: expected the pool nodes %v, got %v", c.platform, c.nodes, nodes)
+		}
+		if vars["tectonic_worker_count"] != float64(3) {
+			t.Errorf("test case %s: expected 3 workers, got %v", c.platform, vars["tectonic_worker_count"])
+		}
+		// The settings of several pools do not override those of the role.
+		if v, ok := vars["tectonic_aws_worker_ec2_type"]; ok && v == "p2.xlarge" {
+			t.Errorf("test case %s: expected the worker role settings to be kept, got the EC2 type %v", c.platform, v)
+		}
+	}
+}
+
+func TestIgnitionWorker(t *testing.T) {
+	if expected := WorkerPoolIgnition("worker"); IgnitionWorker != expected {
+		t.Errorf("expected the deprecated worker ignition path to be the one of the worker pool, %q, got %q", expected, IgnitionWorker)
+	}
+}
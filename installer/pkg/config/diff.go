@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ImmutableFields lists the paths of the fields which cannot change once a
+// cluster is installed, as changing them would require recreating it.
+var ImmutableFields = []string{
+	"name",
+	"baseDomain",
+	"platform",
+	"networking.podCIDR",
+	"networking.serviceCIDR",
+	"aws.vpcCIDRBlock",
+	"libvirt.network.ipRange",
+}
+
+// Change is a difference of a field between two cluster configs.
+type Change struct {
+	// Path is the YAML path of the field, e.g. "networking.podCIDR".
+	Path string
+	// Old and New are the values of the field, or nil if it is not set.
+	Old interface{}
+	New interface{}
+}
+
+// Immutable returns whether the changed field is one of ImmutableFields, or
+// is nested in one of them.
+func (c Change) Immutable() bool {
+	for _, f := range ImmutableFields {
+		if c.Path == f || strings.HasPrefix(c.Path, f+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff returns the changes of the fields of the old cluster config in the
+// current one, sorted by path. Fields are compared by their YAML
+// representation, so fields not written to YAML are ignored.
+func Diff(old, current *Cluster) ([]Change, error) {
+	a, err := yamlValue(old)
+	if err != nil {
+		return nil, err
+	}
+	b, err := yamlValue(current)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	diffValues("", a, b, &changes)
+	return changes, nil
+}
+
+func yamlValue(c *Cluster) (interface{}, error) {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func diffValues(path string, a, b interface{}, changes *[]Change) {
+	switch {
+	case isMap(a) && isMap(b):
+		am, _ := a.(map[interface{}]interface{})
+		bm, _ := b.(map[interface{}]interface{})
+		keys := make(map[string]interface{})
+		for k := range am {
+			keys[fmt.Sprint(k)] = k
+		}
+		for k := range bm {
+			keys[fmt.Sprint(k)] = k
+		}
+		var names []string
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			p := name
+			if path != "" {
+				p = path + "." + name
+			}
+			diffValues(p, am[keys[name]], bm[keys[name]], changes)
+		}
+	case isSlice(a) && isSlice(b):
+		as, _ := a.([]interface{})
+		bs, _ := b.([]interface{})
+		for i := 0; i < len(as) || i < len(bs); i++ {
+			var av, bv interface{}
+			if i < len(as) {
+				av = as[i]
+			}
+			if i < len(bs) {
+				bv = bs[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), av, bv, changes)
+		}
+	case !reflect.DeepEqual(a, b):
+		*changes = append(*changes, Change{Path: path, Old: a, New: b})
+	}
+}
+
+// isMap returns whether v is a YAML mapping, or is unset.
+func isMap(v interface{}) bool {
+	_, ok := v.(map[interface{}]interface{})
+	return ok || v == nil
+}
+
+// isSlice returns whether v is a YAML sequence, or is unset.
+func isSlice(v interface{}) bool {
+	_, ok := v.([]interface{})
+	return ok || v == nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := Cluster{
+		Name:       "test",
+		BaseDomain: "example.com",
+		NodePools:  NodePools{{Name: "master", Count: 1}, {Name: "worker", Count: 2}},
+		Networking: Networking{PodCIDR: "10.2.0.0/16"},
+	}
+
+	cases := []struct {
+		name      string
+		change    func(c *Cluster)
+		expected  []Change
+		immutable []bool
+	}{
+		{
+			name:   "unchanged",
+			change: func(c *Cluster) {},
+		},
+		{
+			name: "node pool count",
+			change: func(c *Cluster) {
+				c.NodePools = NodePools{{Name: "master", Count: 1}, {Name: "worker", Count: 5}}
+			},
+			expected:  []Change{{Path: "nodePools[1].count", Old: 2, New: 5}},
+			immutable: []bool{false},
+		},
+		{
+			name: "name and pod CIDR",
+			change: func(c *Cluster) {
+				c.Name = "other"
+				c.Networking.PodCIDR = ""
+			},
+			expected: []Change{
+				{Path: "name", Old: "test", New: "other"},
+				{Path: "networking.podCIDR", Old: "10.2.0.0/16", New: nil},
+			},
+			immutable: []bool{true, true},
+		},
+	}
+
+	for _, c := range cases {
+		changed := old
+		changed.NodePools = append(NodePools{}, old.NodePools...)
+		c.change(&changed)
+		changes, err := Diff(&old, &changed)
+		if err != nil {
+			t.Errorf("test case %s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(changes, c.expected) {
+			t.Errorf("test case %s: expected changes %v, got %v", c.name, c.expected, changes)
+			continue
+		}
+		for i, change := range changes {
+			if change.Immutable() != c.immutable[i] {
+				t.Errorf("test case %s: expected %s immutable to be %t", c.name, change.Path, c.immutable[i])
+			}
+		}
+	}
+}
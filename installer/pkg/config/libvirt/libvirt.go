@@ -21,6 +21,32 @@ type Libvirt struct {
 	QCOWImagePath string `json:"tectonic_coreos_qcow_path,omitempty" yaml:"imagePath"`
 	Network       `json:",inline" yaml:"network"`
 	MasterIPs     []string `json:"tectonic_libvirt_master_ips,omitempty" yaml:"masterIPs"`
+	Resources     `json:",inline" yaml:"-"`
+}
+
+// Resources holds the memory and vCPUs of the nodes of each role and the
+// pools of the workers, derived from the settings of the node pools.
+type Resources struct {
+	EtcdMemory   int `json:"tectonic_libvirt_etcd_memory,omitempty" yaml:"-"`
+	EtcdVCPU     int `json:"tectonic_libvirt_etcd_vcpu,omitempty" yaml:"-"`
+	MasterMemory int `json:"tectonic_libvirt_master_memory,omitempty" yaml:"-"`
+	MasterVCPU   int `json:"tectonic_libvirt_master_vcpu,omitempty" yaml:"-"`
+	WorkerMemory int `json:"tectonic_libvirt_worker_memory,omitempty" yaml:"-"`
+	WorkerVCPU   int `json:"tectonic_libvirt_worker_vcpu,omitempty" yaml:"-"`
+	// WorkerNodes holds the name of every worker domain, by index, and
+	// WorkerPoolNodes the index of its worker pool.
+	WorkerNodes     []string `json:"tectonic_libvirt_worker_nodes,omitempty" yaml:"-"`
+	WorkerPoolNodes []string `json:"tectonic_libvirt_worker_pool_nodes,omitempty" yaml:"-"`
+	// AppliedWorkerNodes holds the names of the worker domains recorded by
+	// the last apply, by index.
+	AppliedWorkerNodes []string `json:"-" yaml:"-"`
+}
+
+// NodePool converts the libvirt settings of a node pool.
+type NodePool struct {
+	// Memory is the memory of the domains of the pool, in MiB.
+	Memory int `json:"memory,omitempty" yaml:"memory,omitempty"`
+	VCPU   int `json:"vcpu,omitempty" yaml:"vcpu,omitempty"`
 }
 
 // Network describes a libvirt network configuration.
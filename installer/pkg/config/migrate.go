@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+
+	log "github.com/Sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// APIVersion is the current version of the config schema.
+	APIVersion = "installer.tectonic.coreos.com/v1"
+	// Kind is the kind of the cluster configs.
+	Kind = "Cluster"
+	// unversioned is the version of the configs predating apiVersion.
+	unversioned = ""
+)
+
+// migration upgrades a config from a version of the schema to the next one.
+type migration struct {
+	to string
+	// migrate rewrites the fields of the config in place.
+	migrate func(config yaml.MapSlice) (yaml.MapSlice, error)
+}
+
+// migrations maps the versions of the config schema to the migration
+// upgrading configs of that version to the next one.
+var migrations = map[string]migration{}
+
+func registerMigration(from, to string, migrate func(yaml.MapSlice) (yaml.MapSlice, error)) {
+	if _, ok := migrations[from]; ok {
+		panic(fmt.Sprintf("a migration from config version %q is already registered", from))
+	}
+	migrations[from] = migration{to: to, migrate: migrate}
+}
+
+func init() {
+	// Unversioned configs have the same fields as the first version.
+	registerMigration(unversioned, APIVersion, func(config yaml.MapSlice) (yaml.MapSlice, error) {
+		return config, nil
+	})
+}
+
+// ErrUnknownAPIVersion is returned when a config has a version of the
+// schema no migration exists for.
+type ErrUnknownAPIVersion struct {
+	version string
+}
+
+// ErrUnknownAPIVersion implements the error interface.
+func (e *ErrUnknownAPIVersion) Error() string {
+	return fmt.Sprintf("unknown config apiVersion %q, expected %q", e.version, APIVersion)
+}
+
+// ErrInvalidKind is returned when a config has a kind other than Kind.
+type ErrInvalidKind struct {
+	kind string
+}
+
+// ErrInvalidKind implements the error interface.
+func (e *ErrInvalidKind) Error() string {
+	return fmt.Sprintf("invalid config kind %q, expected %q", e.kind, Kind)
+}
+
+// Migrate upgrades the given YAML config to the current version of the
+// schema, applying the migrations of every version in between in order.
+// It returns the version the config had, and the upgraded config, which is
+// data itself if the config already is at the current version.
+// Comments are not kept by the upgrade.
+func Migrate(data []byte) (string, []byte, error) {
+	var config yaml.MapSlice
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return "", nil, err
+	}
+
+	version, _ := mapSliceValue(config, "apiVersion").(string)
+	if kind, ok := mapSliceValue(config, "kind").(string); ok && kind != Kind {
+		return version, nil, &ErrInvalidKind{kind: kind}
+	}
+	if version == APIVersion {
+		return version, data, nil
+	}
+
+	for v := version; v != APIVersion; {
+		m, ok := migrations[v]
+		if !ok {
+			return version, nil, &ErrUnknownAPIVersion{version: v}
+		}
+		var err error
+		if config, err = m.migrate(config); err != nil {
+			return version, nil, fmt.Errorf("failed to migrate config from version %q to %q: %v", v, m.to, err)
+		}
+		v = m.to
+	}
+
+	// The header is set once every migration ran, heading the config.
+	header := yaml.MapSlice{{Key: "apiVersion", Value: APIVersion}, {Key: "kind", Value: Kind}}
+	for _, item := range config {
+		if item.Key != "apiVersion" && item.Key != "kind" {
+			header = append(header, item)
+		}
+	}
+	migrated, err := yaml.Marshal(header)
+	if err != nil {
+		return version, nil, err
+	}
+	return version, migrated, nil
+}
+
+// mapSliceValue returns the value of the given key of the map slice, or nil
+// if it is not set.
+func mapSliceValue(m yaml.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// migrateConfig upgrades the given config, warning if it had to be upgraded.
+func migrateConfig(data []byte) ([]byte, error) {
+	version, migrated, err := Migrate(data)
+	if err != nil {
+		return nil, err
+	}
+	if version != APIVersion {
+		log.Warnf("the config has the outdated apiVersion %q, upgrade it to %q with 'tectonic config migrate'", version, APIVersion)
+	}
+	return migrated, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		version  string
+		expected string
+		err      bool
+	}{
+		{
+			name:     "unversioned",
+			data:     "name: test\nplatform: aws\n",
+			version:  "",
+			expected: "apiVersion: " + APIVersion + "\nkind: Cluster\nname: test\nplatform: aws\n",
+		},
+		{
+			name:     "current",
+			data:     "apiVersion: " + APIVersion + "\nkind: Cluster\nname: test\n",
+			version:  APIVersion,
+			expected: "apiVersion: " + APIVersion + "\nkind: Cluster\nname: test\n",
+		},
+		{
+			name:    "unknown version",
+			data:    "apiVersion: installer.tectonic.coreos.com/v99\nname: test\n",
+			version: "installer.tectonic.coreos.com/v99",
+			err:     true,
+		},
+		{
+			name: "invalid kind",
+			data: "apiVersion: " + APIVersion + "\nkind: Pod\n",
+			err:  true,
+		},
+	}
+
+	for _, c := range cases {
+		version, migrated, err := Migrate([]byte(c.data))
+		if (err != nil) != c.err {
+			t.Errorf("test case %s: expected error %t, got %v", c.name, c.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if version != c.version {
+			t.Errorf("test case %s: expected version %q, got %q", c.name, c.version, version)
+		}
+		if string(migrated) != c.expected {
+			t.Errorf("test case %s: expected config %q, got %q", c.name, c.expected, migrated)
+		}
+	}
+}
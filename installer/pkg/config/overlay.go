@@ -0,0 +1,313 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// referenceRegexp matches the references interpolated in the string values
+// of a config: ${VAR} is the value of an environment variable, ${file:path}
+// the content of a file, and $${ an escaped, literal ${.
+var referenceRegexp = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// ErrUnresolvedReference is returned when a reference in a config cannot
+// be interpolated.
+type ErrUnresolvedReference struct {
+	// Path is the YAML path of the value holding the reference.
+	Path      string
+	Reference string
+	err       error
+}
+
+// ErrUnresolvedReference implements the error interface.
+func (e *ErrUnresolvedReference) Error() string {
+	return fmt.Sprintf("%s: cannot resolve ${%s}: %v", e.Path, e.Reference, e.err)
+}
+
+// HasReferences returns whether the string values of the given config have
+// references to interpolate. Comments, and configs which are not valid YAML,
+// have none.
+func HasReferences(data []byte) bool {
+	var config yaml.MapSlice
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return false
+	}
+	found := false
+	interpolate(nil, config, func(match string) (string, error) {
+		if match != "$${" {
+			found = true
+		}
+		return match, nil
+	})
+	return found
+}
+
+// MergeConfigFiles reads the given YAML configs and merges them in order,
+// each config overlaying the ones before it, into a config of the current
+// version of the schema. References in the string values of the configs
+// are kept unresolved, so that the secrets they reference are not written
+// with the merged config, but the paths of ${file:path} references, and of
+// the files secrets are referenced from, are made absolute first, as they
+// are relative to the config holding them. The references are resolved by
+// InterpolateConfig.
+//
+// Mappings are merged key by key. Sequences of mappings which all have a
+// name, like nodePools, are merged item by item by name, new items being
+// appended. Other values, including other sequences, are replaced, and a
+// null value removes the key, restoring its default.
+func MergeConfigFiles(paths ...string) ([]byte, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no config file given")
+	}
+
+	var merged yaml.MapSlice
+	for _, path := range paths {
+		config, err := readConfigLayer(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		merged = mergeMapSlices(merged, config)
+	}
+	return yaml.Marshal(merged)
+}
+
+// InterpolateConfig returns the given YAML config with the references in
+// its string values resolved, the paths of ${file:path} references being
+// relative to the given directory.
+func InterpolateConfig(data []byte, dir string) ([]byte, error) {
+	var config yaml.MapSlice
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	v, err := interpolate(nil, config, referenceResolver(dir))
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// readConfigLayer reads and upgrades the config at the given path, checking
+// it has no unknown keys, and makes the paths of its ${file:path} references
+// and of its secret files absolute.
+func readConfigLayer(path string) (yaml.MapSlice, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	migrated, err := migrateConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnknownFields(migrated, data); err != nil {
+		return nil, err
+	}
+
+	var config yaml.MapSlice
+	if err := yaml.Unmarshal(migrated, &config); err != nil {
+		return nil, err
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	v, err := interpolate(nil, config, fileReferenceRebaser(dir))
+	if err != nil {
+		return nil, err
+	}
+	config, _ = v.(yaml.MapSlice)
+	rebaseSecretFiles(config, dir)
+	return config, nil
+}
+
+// interpolate returns the given YAML value, at the given path, with the
+// references of its strings, and their $${ escapes, replaced by the given
+// function.
+func interpolate(path []string, value interface{}, replace func(match string) (string, error)) (interface{}, error) {
+	switch v := value.(type) {
+	case yaml.MapSlice:
+		out := make(yaml.MapSlice, 0, len(v))
+		for _, item := range v {
+			interpolated, err := interpolate(append(path, fmt.Sprint(item.Key)), item.Value, replace)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, yaml.MapItem{Key: item.Key, Value: interpolated})
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for i, item := range v {
+			p := append([]string{}, path...)
+			if len(p) > 0 {
+				p[len(p)-1] = fmt.Sprintf("%s[%d]", p[len(p)-1], i)
+			}
+			interpolated, err := interpolate(p, item, replace)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, interpolated)
+		}
+		return out, nil
+	case string:
+		return interpolateString(strings.Join(path, "."), v, replace)
+	}
+	return value, nil
+}
+
+func interpolateString(path, s string, replace func(match string) (string, error)) (string, error) {
+	var err error
+	out := referenceRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		if err != nil {
+			return match
+		}
+		var value string
+		if value, err = replace(match); err != nil {
+			err = &ErrUnresolvedReference{Path: path, Reference: match[2 : len(match)-1], err: err}
+		}
+		return value
+	})
+	return out, err
+}
+
+// referenceResolver returns the function resolving the references, the
+// paths of ${file:path} references being relative to the given directory.
+func referenceResolver(dir string) func(match string) (string, error) {
+	return func(match string) (string, error) {
+		if match == "$${" {
+			return "${", nil
+		}
+		return resolveReference(match[2:len(match)-1], dir)
+	}
+}
+
+// fileReferenceRebaser returns the function making the relative paths of
+// the ${file:path} references absolute, from the given directory, leaving
+// the references unresolved.
+func fileReferenceRebaser(dir string) func(match string) (string, error) {
+	return func(match string) (string, error) {
+		if !strings.HasPrefix(match, "${file:") {
+			return match, nil
+		}
+		path := match[len("${file:") : len(match)-1]
+		if filepath.IsAbs(path) {
+			return match, nil
+		}
+		return "${file:" + filepath.Join(dir, path) + "}", nil
+	}
+}
+
+// resolveReference returns the value of the given reference, without its
+// ${ and }.
+func resolveReference(reference, dir string) (string, error) {
+	if strings.HasPrefix(reference, "file:") {
+		path := strings.TrimPrefix(reference, "file:")
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	if reference == "" {
+		return "", errors.New("empty reference")
+	}
+	value, ok := os.LookupEnv(reference)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", reference)
+	}
+	return value, nil
+}
+
+// mergeMapSlices returns the overlay merged over the base mapping. The keys
+// of the base keep their order, the new keys of the overlay follow.
+func mergeMapSlices(base, overlay yaml.MapSlice) yaml.MapSlice {
+	merged := append(yaml.MapSlice{}, base...)
+	for _, item := range overlay {
+		i := mapSliceIndex(merged, item.Key)
+		switch {
+		case item.Value == nil && i >= 0:
+			merged = append(merged[:i], merged[i+1:]...)
+		case item.Value == nil:
+		case i >= 0:
+			merged[i].Value = mergeValues(merged[i].Value, item.Value)
+		default:
+			merged = append(merged, yaml.MapItem{Key: item.Key, Value: mergeValues(nil, item.Value)})
+		}
+	}
+	return merged
+}
+
+// mergeValues returns the overlay value merged over the base value.
+func mergeValues(base, overlay interface{}) interface{} {
+	switch o := overlay.(type) {
+	case yaml.MapSlice:
+		// Merging over nothing drops the null values of the overlay.
+		b, _ := base.(yaml.MapSlice)
+		return mergeMapSlices(b, o)
+	case []interface{}:
+		if b, ok := base.([]interface{}); ok && namedItems(b) && namedItems(o) {
+			return mergeNamedItems(b, o)
+		}
+	}
+	return overlay
+}
+
+// mergeNamedItems merges the overlay items over the base items of the same
+// name, appending the others.
+func mergeNamedItems(base, overlay []interface{}) []interface{} {
+	merged := append([]interface{}{}, base...)
+	for _, o := range overlay {
+		item := o.(yaml.MapSlice)
+		found := false
+		for i, b := range merged {
+			if mapSliceValue(b.(yaml.MapSlice), "name") == mapSliceValue(item, "name") {
+				merged[i] = mergeMapSlices(b.(yaml.MapSlice), item)
+				found = true
+				break
+			}
+		}
+		if !found {
+			merged = append(merged, mergeMapSlices(nil, item))
+		}
+	}
+	return merged
+}
+
+// namedItems returns whether the given sequence has items, which all are
+// mappings with a name.
+func namedItems(items []interface{}) bool {
+	if len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		m, ok := item.(yaml.MapSlice)
+		if !ok {
+			return false
+		}
+		if _, ok := mapSliceValue(m, "name").(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// mapSliceIndex returns the index of the given key in the map slice, or -1
+// if it is not set.
+func mapSliceIndex(m yaml.MapSlice, key interface{}) int {
+	for i, item := range m {
+		if item.Key == key {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeConfigFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overlay")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"base.yaml": `name: test
+baseDomain: example.com
+platform: aws
+aws:
+  region: eu-west-1
+  extraTags:
+    team: infra
+  master:
+    extraSGIDs: [sg-1, sg-2]
+admin:
+  email: ${TEST_OVERLAY_EMAIL}
+  password: ${file:password}
+nodePools:
+  - name: master
+    count: 1
+  - name: worker
+    count: 1
+`,
+		"prod.yaml": `baseDomain: prod.example.com
+aws:
+  region: us-east-1
+  extraTags:
+    env: prod
+  master:
+    extraSGIDs: [sg-3]
+networking:
+  mtu: ~
+nodePools:
+  - name: worker
+    count: 5
+  - name: etcd
+    count: 3
+`,
+		"password":        "secret\n",
+		"escaped.yaml":    "name: $${NOT_A_REFERENCE}\n",
+		"unresolved.yaml": "name: ${TEST_OVERLAY_UNSET}\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	os.Setenv("TEST_OVERLAY_EMAIL", "admin@example.com")
+	defer os.Unsetenv("TEST_OVERLAY_EMAIL")
+
+	cases := []struct {
+		name     string
+		files    []string
+		expected func(c *Cluster)
+		err      bool
+	}{
+		{
+			name:  "single config",
+			files: []string{"base.yaml"},
+			expected: func(c *Cluster) {
+				c.Name = "test"
+				c.BaseDomain = "example.com"
+				c.Platform = PlatformAWS
+				c.AWS.ExtraTags = map[string]string{"team": "infra"}
+				c.AWS.Master.ExtraSGIDs = []string{"sg-1", "sg-2"}
+				c.Admin = Admin{Email: "admin@example.com", Password: "secret"}
+				c.NodePools = NodePools{{Name: "master", Count: 1}, {Name: "worker", Count: 1}}
+			},
+		},
+		{
+			name:  "overlay",
+			files: []string{"base.yaml", "prod.yaml"},
+			expected: func(c *Cluster) {
+				c.Name = "test"
+				c.BaseDomain = "prod.example.com"
+				c.Platform = PlatformAWS
+				c.AWS.Region = "us-east-1"
+				c.AWS.ExtraTags = map[string]string{"team": "infra", "env": "prod"}
+				c.AWS.Master.ExtraSGIDs = []string{"sg-3"}
+				c.Admin = Admin{Email: "admin@example.com", Password: "secret"}
+				c.NodePools = NodePools{{Name: "master", Count: 1}, {Name: "worker", Count: 5}, {Name: "etcd", Count: 3}}
+			},
+		},
+		{
+			name:  "escaped reference",
+			files: []string{"escaped.yaml"},
+			expected: func(c *Cluster) {
+				c.Name = "${NOT_A_REFERENCE}"
+			},
+		},
+		{
+			name:  "unresolved reference",
+			files: []string{"base.yaml", "unresolved.yaml"},
+			err:   true,
+		},
+	}
+
+	for _, c := range cases {
+		var paths []string
+		for _, f := range c.files {
+			paths = append(paths, filepath.Join(dir, f))
+		}
+		merged, err := MergeConfigFiles(paths...)
+		if err == nil {
+			merged, err = InterpolateConfig(merged, "")
+		}
+		if c.err {
+			if err == nil {
+				t.Errorf("test case %s: expected an error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test case %s: failed to merge configs: %v", c.name, err)
+			continue
+		}
+		cluster, err := ParseConfig(merged)
+		if err != nil {
+			t.Errorf("test case %s: failed to parse the merged config: %v", c.name, err)
+			continue
+		}
+
+		expected := defaultCluster
+		c.expected(&expected)
+		expected.applyPlatformDefaults()
+		if !reflect.DeepEqual(*cluster, expected) {
+			t.Errorf("test case %s: expected %+v, got %+v", c.name, expected, *cluster)
+		}
+	}
+}
+
+func TestMergeConfigFilesKeepsReferences(t *testing.T) {
+	dir, err := ioutil.TempDir("", "overlay")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "config.yaml")
+	config := "name: test\nadmin:\n  email: ${TEST_OVERLAY_EMAIL}\n  password: ${file:password}\n"
+	if err := ioutil.WriteFile(configPath, []byte(config), 0644); err != nil {
+		t.Fatalf("failed to write the config: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "password"), []byte("secret\n"), 0600); err != nil {
+		t.Fatalf("failed to write the password: %v", err)
+	}
+	os.Setenv("TEST_OVERLAY_EMAIL", "admin@example.com")
+	defer os.Unsetenv("TEST_OVERLAY_EMAIL")
+
+	merged, err := MergeConfigFiles(configPath)
+	if err != nil {
+		t.Fatalf("failed to merge the config: %v", err)
+	}
+	for _, s := range []string{"admin@example.com", "secret"} {
+		if strings.Contains(string(merged), s) {
+			t.Errorf("expected the merged config not to hold the resolved value %q, got:\n%s", s, merged)
+		}
+	}
+	if expected := "${file:" + filepath.Join(dir, "password") + "}"; !strings.Contains(string(merged), expected) {
+		t.Errorf("expected the merged config to hold %q, got:\n%s", expected, merged)
+	}
+
+	// The file references stay valid from another directory.
+	mergedPath := filepath.Join(dir, "cluster", "config.yaml")
+	if err := os.Mkdir(filepath.Dir(mergedPath), 0755); err != nil {
+		t.Fatalf("failed to create the cluster directory: %v", err)
+	}
+	if err := ioutil.WriteFile(mergedPath, merged, 0644); err != nil {
+		t.Fatalf("failed to write the merged config: %v", err)
+	}
+	cluster, err := ParseConfigFile(mergedPath)
+	if err != nil {
+		t.Fatalf("failed to parse the merged config: %v", err)
+	}
+	if cluster.Admin.Email != "admin@example.com" || cluster.Admin.Password != "secret" {
+		t.Errorf("expected the references to be resolved on parsing, got %+v", cluster.Admin)
+	}
+}
+
+func TestHasReferences(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		expected bool
+	}{
+		{
+			name:     "environment reference",
+			data:     "admin:\n  email: ${ADMIN_EMAIL}\n",
+			expected: true,
+		},
+		{
+			name:     "file reference in a sequence",
+			data:     "aws:\n  extraTags:\n    - ${file:tags}\n",
+			expected: true,
+		},
+		{
+			name: "escaped reference",
+			data: "admin:\n  password: $${not-a-reference}\n",
+		},
+		{
+			name: "reference in a comment",
+			data: "# set the password to ${file:password}\nname: test\n",
+		},
+	}
+
+	for _, c := range cases {
+		if got := HasReferences([]byte(c.data)); got != c.expected {
+			t.Errorf("test case %s: expected %t, got %t", c.name, c.expected, got)
+		}
+	}
+}
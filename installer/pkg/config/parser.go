@@ -2,29 +2,67 @@ package config
 
 import (
 	"io/ioutil"
+	"path/filepath"
 
 	"gopkg.in/yaml.v2"
 )
 
 // ParseConfig parses a yaml string and returns, if successful, a Cluster.
+// Configs of older versions of the schema are upgraded to the current one.
+// Keys which map to no field of the config are reported as ErrUnknownFields.
+// Only the defaults of the selected platform are applied.
 func ParseConfig(data []byte) (*Cluster, error) {
+	migrated, err := migrateConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkUnknownFields(migrated, data); err != nil {
+		return nil, err
+	}
+
 	cluster := defaultCluster
 
-	if err := yaml.Unmarshal(data, &cluster); err != nil {
+	if err := yaml.Unmarshal(migrated, &cluster); err != nil {
 		return nil, err
 	}
+	cluster.applyPlatformDefaults()
 
 	return &cluster, nil
 }
 
 // ParseConfigFile parses a yaml file and returns, if successful, a Cluster.
+// The references of the file are resolved, the paths of ${file:path}
+// references, and of the files secrets are referenced from, being relative
+// to the file. Unknown keys are reported at their line in the file, before
+// the references are resolved.
 func ParseConfigFile(path string) (*Cluster, error) {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	if HasReferences(data) {
+		migrated, err := migrateConfig(data)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkUnknownFields(migrated, data); err != nil {
+			return nil, err
+		}
+		if data, err = InterpolateConfig(data, dir); err != nil {
+			return nil, err
+		}
+	}
 
-	return ParseConfig(data)
+	cluster, err := ParseConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	cluster.rebaseSecretFiles(dir)
+	return cluster, nil
 }
 
 // ParseInternal parses a yaml string and returns, if successful, an internal.
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/coreos/tectonic-config/config/tectonic-network"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config/aws"
+)
+
+// jsonSchemaDraft is the JSON Schema version of the generated schema.
+const jsonSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// schemaEnums lists the values of the string types of the config which
+// only take a fixed set of values.
+var schemaEnums = map[reflect.Type][]string{
+	reflect.TypeOf(Platform("")): {
+		string(PlatformAWS),
+		string(PlatformLibvirt),
+	},
+	reflect.TypeOf(ContainerLinuxChannel("")): {
+		string(ContainerLinuxChannelStable),
+		string(ContainerLinuxChannelBeta),
+		string(ContainerLinuxChannelAlpha),
+	},
+	reflect.TypeOf(aws.Endpoints("")): {
+		string(aws.EndpointsAll),
+		string(aws.EndpointsPrivate),
+		string(aws.EndpointsPublic),
+	},
+	reflect.TypeOf(tectonicnetwork.NetworkType("")): {
+		string(tectonicnetwork.NetworkNone),
+		string(tectonicnetwork.NetworkFlannel),
+		string(tectonicnetwork.NetworkCanal),
+		string(tectonicnetwork.NetworkCalicoIPIP),
+	},
+}
+
+// schemaTypes overrides the JSON types of the fields, by YAML path, whose
+// Go type is not the only type they accept.
+var schemaTypes = map[string][]string{
+	// The MTU is a string, but is usually written as a number.
+	"networking.mtu": {"integer", "string"},
+}
+
+// Schema is a JSON Schema describing a value of the config.
+type Schema struct {
+	Schema      string             `json:"$schema,omitempty"`
+	Title       string             `json:"title,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Type        interface{}        `json:"type,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	// AdditionalProperties is false for the structs of the config, whose
+	// keys are all known, or the schema of the values of a map.
+	AdditionalProperties interface{} `json:"additionalProperties,omitempty"`
+	Items                *Schema     `json:"items,omitempty"`
+}
+
+// JSONSchema returns the JSON Schema of the cluster config, generated from
+// the Cluster type, for editors to complete and check config files.
+func JSONSchema() ([]byte, error) {
+	s := typeSchema(nil, reflect.TypeOf(Cluster{}))
+	s.Schema = jsonSchemaDraft
+	s.Title = "Tectonic cluster config"
+	s.Description = fmt.Sprintf("The config of a Tectonic cluster, of apiVersion %q.", APIVersion)
+
+	// Older versions are accepted, and upgraded when the config is parsed.
+	var versions []string
+	for v := range migrations {
+		if v != unversioned {
+			versions = append(versions, v)
+		}
+	}
+	versions = append(versions, APIVersion)
+	sort.Strings(versions)
+	s.Properties["apiVersion"].Enum = versions
+	s.Properties["kind"].Enum = []string{Kind}
+
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// typeSchema returns the schema of the field of the given type at the
+// given YAML path.
+func typeSchema(path []string, t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if types, ok := schemaTypes[strings.Join(path, ".")]; ok {
+		return &Schema{Type: types}
+	}
+	if values, ok := schemaEnums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		s := &Schema{
+			Type:                 "object",
+			Properties:           make(map[string]*Schema),
+			AdditionalProperties: false,
+		}
+		for key, field := range yamlFields(t) {
+			s.Properties[key] = typeSchema(append(path, key), field.Type)
+		}
+		return s
+	case reflect.Map:
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: typeSchema(append(path, "*"), t.Elem()),
+		}
+	case reflect.Slice, reflect.Array:
+		return &Schema{
+			Type:  "array",
+			Items: typeSchema(append(path, "*"), t.Elem()),
+		}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	}
+	// Any value is accepted for the types which have no JSON equivalent.
+	return &Schema{}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("failed to generate the schema: %v", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("the schema is not valid JSON: %v", err)
+	}
+
+	cases := []struct {
+		path     string
+		expected interface{}
+	}{
+		{path: "$schema", expected: jsonSchemaDraft},
+		{path: "additionalProperties", expected: false},
+		{path: "properties.kind.enum", expected: []interface{}{Kind}},
+		{path: "properties.apiVersion.enum", expected: []interface{}{APIVersion}},
+		{path: "properties.platform.enum", expected: []interface{}{"aws", "libvirt"}},
+		{path: "properties.containerLinux.properties.channel.enum", expected: []interface{}{"stable", "beta", "alpha"}},
+		{path: "properties.aws.properties.endpoints.enum", expected: []interface{}{"all", "private", "public"}},
+		{path: "properties.aws.properties.master.properties.rootVolume.properties.iops.type", expected: "integer"},
+		{path: "properties.aws.properties.extraTags.additionalProperties.type", expected: "string"},
+		{path: "properties.networking.properties.type.enum", expected: []interface{}{"none", "flannel", "canal", "calico-ipip"}},
+		{path: "properties.networking.properties.mtu.type", expected: []interface{}{"integer", "string"}},
+		{path: "properties.nodePools.items.properties.count.type", expected: "integer"},
+		{path: "properties.nodePools.items.additionalProperties", expected: false},
+		{path: "properties.libvirt.properties.network.properties.ipRange.type", expected: "string"},
+		// Fields which are not part of the YAML config are left out.
+		{path: "properties.clusterId", expected: nil},
+		{path: "properties.etcd.properties.count", expected: nil},
+	}
+
+	for _, c := range cases {
+		var v interface{} = schema
+		for _, key := range strings.Split(c.path, ".") {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				v = nil
+				break
+			}
+			v = m[key]
+		}
+		if !reflect.DeepEqual(v, c.expected) {
+			t.Errorf("test case %s: expected %v, got %v", c.path, c.expected, v)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// generatedPasswordLength is the length of the generated admin passwords.
+const generatedPasswordLength = 24
+
+// passwordAlphabet holds the characters of the generated admin passwords.
+// Quotes and backslashes are left out, as they need escaping in tfvars.
+const passwordAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.,:;!?@#%^&*+=()[]{}<>~"
+
+// secretRefPaths holds the YAML paths of the secret references of a config.
+var secretRefPaths = [][]string{{"admin", "passwordFrom"}, {"pullSecretFrom"}, {"licenseFrom"}}
+
+// SecretRef references a secret kept out of the config, in either a file or
+// an environment variable. It is only resolved when the secret is used.
+type SecretRef struct {
+	// File is the path of the file holding the secret. A relative path is
+	// relative to the config holding the reference.
+	File string `json:"-" yaml:"file,omitempty"`
+	// Env is the name of the environment variable holding the secret.
+	Env string `json:"-" yaml:"env,omitempty"`
+}
+
+// String returns a description of the reference.
+func (r *SecretRef) String() string {
+	if r.File != "" {
+		return "file " + r.File
+	}
+	return "environment variable " + r.Env
+}
+
+// Validate returns an error unless exactly one source of the secret is set.
+func (r *SecretRef) Validate() error {
+	switch {
+	case r.File != "" && r.Env != "":
+		return errors.New("only one of file and env can be set")
+	case r.File == "" && r.Env == "":
+		return errors.New("one of file and env must be set")
+	}
+	return nil
+}
+
+// Resolve returns the secret. The trailing newlines of a file are dropped.
+func (r *SecretRef) Resolve() (string, error) {
+	if err := r.Validate(); err != nil {
+		return "", err
+	}
+	if r.File != "" {
+		data, err := ioutil.ReadFile(r.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret from %s: %v", r, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	value, ok := os.LookupEnv(r.Env)
+	if !ok {
+		return "", fmt.Errorf("failed to read secret from %s: it is not set", r)
+	}
+	return value, nil
+}
+
+// HasRelativeSecretFiles returns whether the given config references secrets
+// from files by relative paths.
+func HasRelativeSecretFiles(data []byte) bool {
+	var config yaml.MapSlice
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return false
+	}
+	for _, item := range secretFileItems(config) {
+		if path, ok := item.Value.(string); ok && path != "" && !filepath.IsAbs(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// secretFileItems returns the file items of the secret references of the
+// given config.
+func secretFileItems(config yaml.MapSlice) []*yaml.MapItem {
+	var items []*yaml.MapItem
+	for _, path := range secretRefPaths {
+		m := config
+		for _, key := range path {
+			i := mapSliceIndex(m, key)
+			if i < 0 {
+				m = nil
+				break
+			}
+			m, _ = m[i].Value.(yaml.MapSlice)
+		}
+		if i := mapSliceIndex(m, "file"); i >= 0 {
+			items = append(items, &m[i])
+		}
+	}
+	return items
+}
+
+// rebaseSecretFiles makes the relative paths of the files the given config
+// references secrets from absolute, from the given directory.
+func rebaseSecretFiles(config yaml.MapSlice, dir string) {
+	for _, item := range secretFileItems(config) {
+		if path, ok := item.Value.(string); ok && path != "" && !filepath.IsAbs(path) {
+			item.Value = filepath.Join(dir, path)
+		}
+	}
+}
+
+// rebaseSecretFiles makes the relative paths of the files the config
+// references secrets from absolute, from the given directory.
+func (c *Cluster) rebaseSecretFiles(dir string) {
+	for _, ref := range []*SecretRef{c.Admin.PasswordFrom, c.PullSecretFrom, c.LicenseFrom} {
+		if ref != nil && ref.File != "" && !filepath.IsAbs(ref.File) {
+			ref.File = filepath.Join(dir, ref.File)
+		}
+	}
+}
+
+// GeneratePassword returns a random password for the admin user.
+func GeneratePassword() (string, error) {
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	password := make([]byte, generatedPasswordLength)
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %v", err)
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSecretRefResolve(t *testing.T) {
+	f, err := ioutil.TempFile("", "secret")
+	if err != nil {
+		t.Fatalf("failed to create secret file: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("from-file\n"); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+	f.Close()
+	os.Setenv("TEST_SECRET_REF", "from-env")
+	defer os.Unsetenv("TEST_SECRET_REF")
+
+	cases := []struct {
+		name     string
+		ref      SecretRef
+		expected string
+		err      bool
+	}{
+		{name: "file", ref: SecretRef{File: f.Name()}, expected: "from-file"},
+		{name: "env", ref: SecretRef{Env: "TEST_SECRET_REF"}, expected: "from-env"},
+		{name: "missing file", ref: SecretRef{File: f.Name() + ".missing"}, err: true},
+		{name: "unset env", ref: SecretRef{Env: "TEST_SECRET_REF_UNSET"}, err: true},
+		{name: "both", ref: SecretRef{File: f.Name(), Env: "TEST_SECRET_REF"}, err: true},
+		{name: "none", ref: SecretRef{}, err: true},
+	}
+
+	for _, c := range cases {
+		secret, err := c.ref.Resolve()
+		if (err != nil) != c.err {
+			t.Errorf("test case %s: expected error %t, got %v", c.name, c.err, err)
+			continue
+		}
+		if secret != c.expected {
+			t.Errorf("test case %s: expected %q, got %q", c.name, c.expected, secret)
+		}
+	}
+}
+
+func TestValidateAdminPassword(t *testing.T) {
+	cases := []struct {
+		name     string
+		admin    Admin
+		field    string
+		severity Severity
+	}{
+		{name: "plain text", admin: Admin{Password: "secret"}, field: "admin.password", severity: SeverityWarning},
+		{name: "empty", admin: Admin{}, field: "admin.password", severity: SeverityError},
+		{name: "both", admin: Admin{Password: "secret", PasswordFrom: &SecretRef{Env: "PASSWORD"}}, field: "admin.password", severity: SeverityError},
+		{name: "invalid reference", admin: Admin{PasswordFrom: &SecretRef{}}, field: "admin.passwordFrom", severity: SeverityError},
+		{name: "reference", admin: Admin{PasswordFrom: &SecretRef{Env: "PASSWORD"}}},
+	}
+
+	for _, c := range cases {
+		cluster := Cluster{Admin: c.admin}
+		var found *ValidationError
+		for _, e := range cluster.Check(nil) {
+			if strings.HasPrefix(e.Field, "admin.password") {
+				found = e
+			}
+		}
+		switch {
+		case c.field == "" && found != nil:
+			t.Errorf("test case %s: expected no error, got %v", c.name, found)
+		case c.field == "":
+		case found == nil:
+			t.Errorf("test case %s: expected an error of %s", c.name, c.field)
+		case found.Field != c.field || found.Severity != c.severity:
+			t.Errorf("test case %s: expected %s of %s, got %s of %s", c.name, c.severity, c.field, found.Severity, found.Field)
+		}
+	}
+}
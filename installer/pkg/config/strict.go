@@ -0,0 +1,264 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+var (
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	yamlKeyRegexp   = regexp.MustCompile(`^((?:- +)*)["']?([^"'#:]+?)["']?\s*:(?:\s|$)`)
+)
+
+// ErrUnknownField describes a key of a config which maps to no field.
+type ErrUnknownField struct {
+	// Path is the YAML path of the key, e.g. "aws.vpcCidrBlock".
+	Path string
+	// Line is the line of the key in the config, or 0 if it is unknown.
+	Line int
+	// Suggestion is the known key closest to the unknown one, if any.
+	Suggestion string
+}
+
+// ErrUnknownField implements the error interface.
+func (e *ErrUnknownField) Error() string {
+	msg := fmt.Sprintf("unknown field %q", e.Path)
+	if e.Line > 0 {
+		msg = fmt.Sprintf("line %d: %s", e.Line, msg)
+	}
+	if e.Suggestion != "" {
+		msg = fmt.Sprintf("%s, did you mean %q?", msg, e.Suggestion)
+	}
+	return msg
+}
+
+// ErrUnknownFields lists the unknown keys of a config.
+type ErrUnknownFields []*ErrUnknownField
+
+// ErrUnknownFields implements the error interface.
+func (e ErrUnknownFields) Error() string {
+	var msgs []string
+	for _, f := range e {
+		msgs = append(msgs, f.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// checkUnknownFields returns the keys of the given YAML config which map to
+// no field of Cluster, or nil if there are none. The keys are located in
+// source, the config as written before being migrated.
+func checkUnknownFields(data, source []byte) error {
+	var config yaml.MapSlice
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return err
+	}
+
+	var errs ErrUnknownFields
+	walkUnknownFields(nil, config, reflect.TypeOf(Cluster{}), &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	lines := strings.Split(string(source), "\n")
+	for _, e := range errs {
+		e.Line = findKeyLine(lines, e.Path)
+	}
+	return errs
+}
+
+// walkUnknownFields appends to errs the keys of the given YAML value which
+// map to no field of the given type.
+func walkUnknownFields(path []string, value interface{}, t reflect.Type, errs *ErrUnknownFields) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(yaml.MapSlice)
+		if !ok {
+			return
+		}
+		fields := yamlFields(t)
+		for _, item := range m {
+			key := fmt.Sprint(item.Key)
+			field, ok := fields[key]
+			if !ok {
+				*errs = append(*errs, &ErrUnknownField{
+					Path:       strings.Join(append(path, key), "."),
+					Suggestion: suggestKey(key, fields),
+				})
+				continue
+			}
+			walkUnknownFields(append(path, key), item.Value, field.Type, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		s, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		for i, v := range s {
+			p := append([]string{}, path...)
+			if len(p) > 0 {
+				p[len(p)-1] = fmt.Sprintf("%s[%d]", p[len(p)-1], i)
+			}
+			walkUnknownFields(p, v, t.Elem(), errs)
+		}
+	}
+}
+
+// yamlFields returns the fields of the given struct type by their YAML key.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("yaml")
+		if tag == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if strings.Contains(tag, ",inline") {
+			for k, v := range yamlFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// suggestKey returns the known key closest to the given unknown one, or ""
+// if none is close enough.
+func suggestKey(key string, fields map[string]reflect.StructField) string {
+	best, bestDistance := "", len(key)/3+2
+	for known := range fields {
+		if strings.EqualFold(known, key) {
+			return known
+		}
+		if d := levenshtein(strings.ToLower(key), strings.ToLower(known)); d < bestDistance || (d == bestDistance && best != "" && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// findKeyLine returns the line of the key with the given YAML path in the
+// lines of a block style YAML document, or 0 if it cannot be found.
+func findKeyLine(lines []string, path string) int {
+	// pos is the next line to look at, and parent the column of the key or
+	// sequence item the next segment of the path is nested in.
+	pos, parent, line := 0, -1, 0
+	onItem := false
+	for _, segment := range strings.Split(path, ".") {
+		key, index := segment, -1
+		if i := strings.Index(segment, "["); i >= 0 {
+			key = segment[:i]
+			fmt.Sscanf(segment[i:], "[%d]", &index)
+		}
+
+		// Look up the key among the children of its parent. The first key of
+		// a sequence item is on the line of the item.
+		found, column := false, -1
+		for start := pos; pos < len(lines); pos++ {
+			indent, content := splitIndent(lines[pos])
+			if content == "" || strings.HasPrefix(content, "#") {
+				continue
+			}
+			if indent <= parent && !(onItem && pos == start) {
+				return 0
+			}
+			m := yamlKeyRegexp.FindStringSubmatch(content)
+			if m == nil {
+				continue
+			}
+			c := indent + len(m[1])
+			if column < 0 {
+				column = c
+			}
+			if c == column && m[2] == key {
+				found, line, parent = true, pos+1, c
+				pos++
+				break
+			}
+		}
+		if !found {
+			return 0
+		}
+		onItem = false
+		if index < 0 {
+			continue
+		}
+
+		// Look up the item of the sequence of the key.
+		item, dash := -1, -1
+		for ; pos < len(lines); pos++ {
+			indent, content := splitIndent(lines[pos])
+			if content == "" || strings.HasPrefix(content, "#") {
+				continue
+			}
+			if indent < parent || (indent == parent && !strings.HasPrefix(content, "-")) {
+				return 0
+			}
+			if !strings.HasPrefix(content, "-") || (dash >= 0 && indent != dash) {
+				continue
+			}
+			dash = indent
+			if item++; item == index {
+				line, parent, onItem = pos+1, dash, true
+				break
+			}
+		}
+		if item != index {
+			return 0
+		}
+	}
+	return line
+}
+
+// splitIndent returns the indentation of the given line and its content.
+func splitIndent(line string) (int, string) {
+	content := strings.TrimLeft(line, " ")
+	return len(line) - len(content), strings.TrimRight(content, " \r")
+}
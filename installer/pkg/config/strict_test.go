@@ -0,0 +1,94 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseConfigUnknownFields(t *testing.T) {
+	cases := []struct {
+		name     string
+		data     string
+		expected ErrUnknownFields
+	}{
+		{
+			name: "known fields",
+			data: "name: test\naws:\n  vpcCIDRBlock: 10.0.0.0/16\n  extraTags:\n    anything: goes\nnodePools:\n  - name: master\n    count: 1\n",
+		},
+		{
+			name: "misspelled keys",
+			data: "name: test\naws:\n  region: eu-west-1\n  vpcCidrBlock: 10.0.0.0/16\nnodepools:\n  - name: master\n",
+			expected: ErrUnknownFields{
+				{Path: "aws.vpcCidrBlock", Line: 4, Suggestion: "vpcCIDRBlock"},
+				{Path: "nodepools", Line: 5, Suggestion: "nodePools"},
+			},
+		},
+		{
+			name: "unknown keys in a sequence",
+			data: "nodePools:\n  - name: etcd\n    count: 3\n  - name: master\n    # a comment\n    cuont: 1\n",
+			expected: ErrUnknownFields{
+				{Path: "nodePools[1].cuont", Line: 6, Suggestion: "count"},
+			},
+		},
+		{
+			name: "unknown key without suggestion",
+			data: "name: test\n\niscsi:\n  enabled: false\n",
+			expected: ErrUnknownFields{
+				{Path: "iscsi", Line: 3},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		_, err := ParseConfig([]byte(c.data))
+		if c.expected == nil {
+			if err != nil {
+				t.Errorf("test case %s: unexpected error: %v", c.name, err)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(err, c.expected) {
+			t.Errorf("test case %s: expected error %v, got %v", c.name, c.expected, err)
+		}
+	}
+}
+
+func TestParseConfigFileUnknownFieldsWithReferences(t *testing.T) {
+	dir, err := ioutil.TempDir("", "strict")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.yaml")
+	data := "# passwords look like ${PASSWORD}\nname: test\nadmin:\n  email: admin@example.com\n\n  password: ${file:admin-password}\niscsi:\n  enabled: false\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "admin-password"), []byte("password"), 0600); err != nil {
+		t.Fatalf("failed to write admin password: %v", err)
+	}
+
+	_, err = ParseConfigFile(path)
+	if expected := (ErrUnknownFields{{Path: "iscsi", Line: 7}}); !reflect.DeepEqual(err, expected) {
+		t.Errorf("expected error %v, got %v", expected, err)
+	}
+}
+
+func TestParseExampleConfigs(t *testing.T) {
+	examples, err := filepath.Glob("../../../examples/tectonic.*.yaml")
+	if err != nil {
+		t.Fatalf("failed to list example configs: %v", err)
+	}
+	if len(examples) == 0 {
+		t.Fatal("found no example configs")
+	}
+	for _, example := range examples {
+		if _, err := ParseConfigFile(example); err != nil {
+			t.Errorf("failed to parse %s: %v", example, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// derivedTFVars lists the variables of the tfvars which are derived from
+// the config rather than mapped to a field of it. The libvirt resources of
+// the roles become settings of their node pools.
+var derivedTFVars = map[string]bool{
+	"tectonic_etcd_count":      true,
+	"tectonic_master_count":    true,
+	"tectonic_worker_count":    true,
+	"tectonic_ignition_etcd":   true,
+	"tectonic_ignition_master": true,
+	// The workers have an ignition config per node pool since
+	// tectonic_worker_pools.
+	"tectonic_ignition_worker": true,
+	"tectonic_worker_pools":    true,
+
+	"tectonic_libvirt_worker_nodes":      true,
+	"tectonic_libvirt_worker_pool_nodes": true,
+
+	"tectonic_libvirt_etcd_memory":   true,
+	"tectonic_libvirt_etcd_vcpu":     true,
+	"tectonic_libvirt_master_memory": true,
+	"tectonic_libvirt_master_vcpu":   true,
+	"tectonic_libvirt_worker_memory": true,
+	"tectonic_libvirt_worker_vcpu":   true,
+}
+
+// tfVar is a variable of the tfvars which maps to a field of the config.
+type tfVar struct {
+	kind reflect.Kind
+	// inConfig is whether the field is part of the YAML config.
+	inConfig bool
+}
+
+// ParseTFVars parses a tfvars.json of the legacy installer into a Cluster.
+// The node counts of the roles become node pools of the roles' names when
+// the config is converted to YAML. Integers may be given as strings.
+//
+// It also returns the variables which are not kept by the conversion,
+// either because the config has no field for them, or because their field
+// is not part of the YAML config, sorted by name.
+func ParseTFVars(data []byte) (*Cluster, []string, error) {
+	var vars map[string]interface{}
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, nil, err
+	}
+
+	fields := make(map[string]tfVar)
+	tfVarFields(reflect.TypeOf(Cluster{}), true, fields)
+
+	var unmapped []string
+	for name, value := range vars {
+		field, ok := fields[name]
+		if !ok && derivedTFVars[name] {
+			continue
+		}
+		if !ok || (!field.inConfig && !derivedTFVars[name]) {
+			unmapped = append(unmapped, name)
+			continue
+		}
+		if s, ok := value.(string); ok && field.kind == reflect.Int {
+			if i, err := strconv.Atoi(s); err == nil {
+				vars[name] = i
+			}
+		}
+	}
+	sort.Strings(unmapped)
+
+	normalized, err := json.Marshal(vars)
+	if err != nil {
+		return nil, nil, err
+	}
+	cluster := &Cluster{}
+	if err := json.Unmarshal(normalized, cluster); err != nil {
+		return nil, nil, err
+	}
+	return cluster, unmapped, nil
+}
+
+// tfVarFields adds the tfvars variables of the fields of the given struct
+// type to vars. inConfig is whether the struct is part of the YAML config.
+func tfVarFields(t reflect.Type, inConfig bool, vars map[string]tfVar) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		fieldInConfig := inConfig && f.Tag.Get("yaml") != "-"
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			tfVarFields(f.Type, fieldInConfig, vars)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		vars[name] = tfVar{kind: f.Type.Kind(), inConfig: fieldInConfig}
+	}
+}
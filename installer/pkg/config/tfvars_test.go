@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTFVarsRoundTrip(t *testing.T) {
+	cases := []struct {
+		file     string
+		unmapped []string
+	}{
+		{
+			file:     "aws-basic.tfvars.json",
+			unmapped: []string{"tectonic_ca_cert", "tectonic_kubelet_debug_config", "tectonic_update_channel"},
+		},
+		{
+			file:     "aws-external.tfvars.json",
+			unmapped: []string{"tectonic_cluster_id"},
+		},
+		{
+			file:     "libvirt.tfvars.json",
+			unmapped: []string{"tectonic_vanilla_k8s"},
+		},
+	}
+
+	for _, c := range cases {
+		data, err := ioutil.ReadFile(filepath.Join("fixtures", "tfvars", c.file))
+		if err != nil {
+			t.Fatalf("test case %s: failed to read tfvars: %v", c.file, err)
+		}
+		cluster, unmapped, err := ParseTFVars(data)
+		if err != nil {
+			t.Errorf("test case %s: failed to parse tfvars: %v", c.file, err)
+			continue
+		}
+		if !reflect.DeepEqual(unmapped, c.unmapped) {
+			t.Errorf("test case %s: expected unmapped variables %v, got %v", c.file, c.unmapped, unmapped)
+		}
+
+		yaml, err := cluster.YAML()
+		if err != nil {
+			t.Errorf("test case %s: failed to convert to YAML: %v", c.file, err)
+			continue
+		}
+		converted, err := ParseConfig([]byte(yaml))
+		if err != nil {
+			t.Errorf("test case %s: failed to parse the converted config: %v\n%s", c.file, err, yaml)
+			continue
+		}
+		tfvars, err := converted.TFVars()
+		if err != nil {
+			t.Errorf("test case %s: failed to convert back to tfvars: %v", c.file, err)
+			continue
+		}
+
+		var original, roundTripped map[string]interface{}
+		if err := json.Unmarshal(data, &original); err != nil {
+			t.Fatalf("test case %s: failed to unmarshal tfvars: %v", c.file, err)
+		}
+		if err := json.Unmarshal([]byte(tfvars), &roundTripped); err != nil {
+			t.Fatalf("test case %s: failed to unmarshal converted tfvars: %v", c.file, err)
+		}
+		for _, name := range unmapped {
+			delete(original, name)
+		}
+		for name, value := range original {
+			// The derived variables the current tfvars replace, such as
+			// tectonic_ignition_worker, are not kept.
+			if _, ok := roundTripped[name]; !ok && derivedTFVars[name] {
+				continue
+			}
+			// Integers given as strings are converted to integers.
+			if got := roundTripped[name]; fmt.Sprint(got) != fmt.Sprint(value) {
+				t.Errorf("test case %s: expected %s to be %v, got %v", c.file, name, value, got)
+			}
+		}
+	}
+}
@@ -1,6 +1,11 @@
 package config
 
-import "github.com/coreos/tectonic-config/config/tectonic-network"
+import (
+	"github.com/coreos/tectonic-config/config/tectonic-network"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config/aws"
+	"github.com/coreos/tectonic-installer/installer/pkg/config/libvirt"
+)
 
 // ContainerLinuxChannel indicates the selected Container Linux channel.
 type ContainerLinuxChannel string
@@ -19,7 +24,11 @@ const (
 // Admin converts admin related config.
 type Admin struct {
 	Email    string `json:"tectonic_admin_email" yaml:"email,omitempty"`
-	Password string `json:"tectonic_admin_password" yaml:"password,omitempty"`
+	Password string `json:"tectonic_admin_password,omitempty" yaml:"password,omitempty"`
+	// PasswordFrom references the password, instead of Password. It is
+	// resolved when TerraForm runs, keeping the password out of the
+	// cluster directory.
+	PasswordFrom *SecretRef `json:"-" yaml:"passwordFrom,omitempty"`
 }
 
 // CA related config
@@ -46,6 +55,19 @@ type NodePool struct {
 	Count        int    `json:"-" yaml:"count"`
 	Name         string `json:"-" yaml:"name"`
 	IgnitionFile string `json:"-" yaml:"ignitionFile"`
+	// AWS and Libvirt hold the platform settings of the nodes of the pool.
+	AWS     *aws.NodePool     `json:"-" yaml:"aws,omitempty"`
+	Libvirt *libvirt.NodePool `json:"-" yaml:"libvirt,omitempty"`
+}
+
+// NodePool returns the node pool of the given name, or nil.
+func (n NodePools) NodePool(name string) *NodePool {
+	for i := range n {
+		if n[i].Name == name {
+			return &n[i]
+		}
+	}
+	return nil
 }
 
 // NodePools converts node pools related config.
@@ -78,6 +100,26 @@ type Networking struct {
 type Worker struct {
 	Count     int      `json:"tectonic_worker_count,omitempty" yaml:"-"`
 	NodePools []string `json:"-" yaml:"nodePools"`
+	// Pools are derived from the node pools of the workers.
+	Pools []WorkerPool `json:"tectonic_worker_pools,omitempty" yaml:"-"`
+}
+
+// WorkerPool converts a worker node pool to the tfvars. Each pool is a
+// group of nodes of its own, with the platform settings of the pool. The
+// settings are strings, as in every Terraform map, and the unset ones
+// fall back to the settings of the worker role.
+type WorkerPool struct {
+	Name           string `json:"name"`
+	Count          string `json:"count"`
+	Ignition       string `json:"ignition"`
+	EC2Type        string `json:"ec2_type,omitempty"`
+	ExtraSGIDs     string `json:"extra_sg_ids,omitempty"`
+	IAMRoleName    string `json:"iam_role_name,omitempty"`
+	RootVolumeIOPS string `json:"root_volume_iops,omitempty"`
+	RootVolumeSize string `json:"root_volume_size,omitempty"`
+	RootVolumeType string `json:"root_volume_type,omitempty"`
+	Memory         string `json:"memory,omitempty"`
+	VCPU           string `json:"vcpu,omitempty"`
 }
 
 // Internal converts internal related config.
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/coreos/tectonic-installer/installer/pkg/config/aws"
+	"github.com/coreos/tectonic-installer/installer/pkg/config/libvirt"
 	"github.com/coreos/tectonic-installer/installer/pkg/validate"
 
 	log "github.com/Sirupsen/logrus"
@@ -21,11 +22,14 @@ const (
 
 var (
 	qcowMagic = []byte{'Q', 'F', 'I', 0xfb}
+
+	workerPoolNameRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
 )
 
 // ErrUnmatchedNodePool is returned when a nodePool was specified but not found in the nodePools list.
 type ErrUnmatchedNodePool struct {
-	name string
+	name  string
+	field string
 }
 
 // ErrUnmatchedNodePool implements the error interface.
@@ -33,6 +37,10 @@ func (e *ErrUnmatchedNodePool) Error() string {
 	return fmt.Sprintf("no node pool named %q was found", e.name)
 }
 
+func (e *ErrUnmatchedNodePool) fieldPath() string {
+	return e.field + ".nodePools"
+}
+
 // ErrMissingNodePool is returned when a field that requires a nodePool does not specify one.
 type ErrMissingNodePool struct {
 	field string
@@ -43,6 +51,10 @@ func (e *ErrMissingNodePool) Error() string {
 	return fmt.Sprintf("the %s field requires at least one node pool to be specified", e.field)
 }
 
+func (e *ErrMissingNodePool) fieldPath() string {
+	return e.field + ".nodePools"
+}
+
 // ErrMoreThanOneNodePool is returned when a field specifies more than one node pool.
 type ErrMoreThanOneNodePool struct {
 	field string
@@ -53,6 +65,10 @@ func (e *ErrMoreThanOneNodePool) Error() string {
 	return fmt.Sprintf("the %s field specifies more than one node pool; this is not currently allowed", e.field)
 }
 
+func (e *ErrMoreThanOneNodePool) fieldPath() string {
+	return e.field + ".nodePools"
+}
+
 // ErrSharedNodePool is returned when two or more fields are defined to use the same nodePool.
 type ErrSharedNodePool struct {
 	name   string
@@ -64,10 +80,15 @@ func (e *ErrSharedNodePool) Error() string {
 	return fmt.Sprintf("node pools cannot be shared, but %q is used by %s", e.name, strings.Join(e.fields, ", "))
 }
 
+func (e *ErrSharedNodePool) fieldPath() string {
+	return "nodePools"
+}
+
 // ErrInvalidIgnConfig is returned when a invalid ign config is given.
 type ErrInvalidIgnConfig struct {
 	filePath string
 	rpt      string
+	field    string
 }
 
 // ErrInvalidIgnConfig implements the error interface.
@@ -75,27 +96,49 @@ func (e *ErrInvalidIgnConfig) Error() string {
 	return fmt.Sprintf("failed to parse ignition file %s: %s", e.filePath, e.rpt)
 }
 
+func (e *ErrInvalidIgnConfig) fieldPath() string {
+	return e.field
+}
+
 // Validate ensures that the Cluster is semantically correct and returns an error if not.
+// Warnings are not returned, see Check.
 func (c *Cluster) Validate() []error {
+	errs, _ := c.validateBySeverity()
+	return errs
+}
+
+// validateBySeverity returns the errors and the warnings of the Cluster.
+func (c *Cluster) validateBySeverity() (errs, warnings []error) {
+	for _, err := range c.validate() {
+		if e, ok := err.(*ValidationError); ok && e.Severity == SeverityWarning {
+			warnings = append(warnings, err)
+			continue
+		}
+		errs = append(errs, err)
+	}
+	return errs, warnings
+}
+
+// validate returns the errors and warnings of the Cluster.
+func (c *Cluster) validate() []error {
 	var errs []error
 	errs = append(errs, c.validateNodePools()...)
 	errs = append(errs, c.validateIgnitionFiles()...)
+	errs = append(errs, c.validateNodePoolSettings()...)
 	errs = append(errs, c.validateNetworking()...)
 	errs = append(errs, c.validateAWS()...)
 	errs = append(errs, c.validateCL()...)
 	errs = append(errs, c.validateTectonicFiles()...)
 	errs = append(errs, c.validateLibvirt()...)
 	errs = append(errs, c.validateCA()...)
-	if err := validate.PrefixError("cluster name", validate.ClusterName(c.Name)); err != nil {
-		errs = append(errs, err)
-	}
-	if err := validate.PrefixError("base domain", validate.DomainName(c.BaseDomain)); err != nil {
+	if err := fieldError("name", c.Name, validate.ClusterName(c.Name)); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("admin password", validate.NonEmpty(c.Admin.Password)); err != nil {
+	if err := fieldError("baseDomain", c.BaseDomain, validate.DomainName(c.BaseDomain)); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("admin email", validate.Email(c.Admin.Email)); err != nil {
+	errs = append(errs, c.validateAdminPassword()...)
+	if err := fieldError("admin.email", c.Admin.Email, validate.Email(c.Admin.Email)); err != nil {
 		errs = append(errs, err)
 	}
 	return errs
@@ -107,20 +150,20 @@ func (c *Cluster) validateAWS() []error {
 	if c.Platform != PlatformAWS {
 		return errs
 	}
-	if err := c.validateAWSEndpoints(); err != nil {
+	if err := fieldError("aws.endpoints", c.AWS.Endpoints, c.validateAWSEndpoints()); err != nil {
 		errs = append(errs, err)
 	}
-	if err := c.validateTNCS3Bucket(); err != nil {
+	if err := fieldError("name", c.Name, c.validateTNCS3Bucket()); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("aws vpcCIDRBlock", validate.SubnetCIDR(c.AWS.VPCCIDRBlock)); err != nil {
+	if err := fieldError("aws.vpcCIDRBlock", c.AWS.VPCCIDRBlock, validate.SubnetCIDR(c.AWS.VPCCIDRBlock)); err != nil {
 		errs = append(errs, err)
 	}
-	errs = append(errs, c.validateOverlapWithPodOrServiceCIDR(c.AWS.VPCCIDRBlock, "aws vpcCIDRBlock")...)
-	if err := validate.PrefixError("aws profile", validate.NonEmpty(c.AWS.Profile)); err != nil {
+	errs = append(errs, c.validateOverlapWithPodOrServiceCIDR(c.AWS.VPCCIDRBlock, "aws.vpcCIDRBlock")...)
+	if err := fieldError("aws.profile", c.AWS.Profile, validate.NonEmpty(c.AWS.Profile)); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("aws region", validate.NonEmpty(c.AWS.Region)); err != nil {
+	if err := fieldError("aws.region", c.AWS.Region, validate.NonEmpty(c.AWS.Region)); err != nil {
 		errs = append(errs, err)
 	}
 	return errs
@@ -137,22 +180,22 @@ func (c *Cluster) validateCL() []error {
 	case ContainerLinuxChannelAlpha:
 		break
 	default:
-		errs = append(errs, fmt.Errorf("invalid Container Linux channel %q", c.ContainerLinux.Channel))
+		errs = append(errs, fieldError("containerLinux.channel", c.ContainerLinux.Channel, fmt.Errorf("invalid Container Linux channel %q", c.ContainerLinux.Channel)))
 	}
 	if c.ContainerLinux.Version != ContainerLinuxVersionLatest && !regexp.MustCompile(`\d+\.\d+\.\d+`).MatchString(c.ContainerLinux.Version) {
-		errs = append(errs, fmt.Errorf("invalid Container Linux version %q", c.ContainerLinux.Version))
+		errs = append(errs, fieldError("containerLinux.version", c.ContainerLinux.Version, fmt.Errorf("invalid Container Linux version %q", c.ContainerLinux.Version)))
 	}
 	return errs
 }
 
-// validateOverlapWithPodOrServiceCIDR ensures that the given CIDR does not
-// overlap with the pod or service CIDRs of the cluster config.
-func (c *Cluster) validateOverlapWithPodOrServiceCIDR(cidr, name string) []error {
+// validateOverlapWithPodOrServiceCIDR ensures that the CIDR of the given
+// field does not overlap with the pod or service CIDRs of the cluster config.
+func (c *Cluster) validateOverlapWithPodOrServiceCIDR(cidr, field string) []error {
 	var errs []error
-	if err := validate.PrefixError(fmt.Sprintf("%s and podCIDR", name), validate.CIDRsDontOverlap(cidr, c.Networking.PodCIDR)); err != nil {
+	if err := fieldError(field, cidr, validate.PrefixError("networking.podCIDR", validate.CIDRsDontOverlap(cidr, c.Networking.PodCIDR))); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError(fmt.Sprintf("%s and serviceCIDR", name), validate.CIDRsDontOverlap(cidr, c.Networking.ServiceCIDR)); err != nil {
+	if err := fieldError(field, cidr, validate.PrefixError("networking.serviceCIDR", validate.CIDRsDontOverlap(cidr, c.Networking.ServiceCIDR))); err != nil {
 		errs = append(errs, err)
 	}
 	return errs
@@ -164,57 +207,57 @@ func (c *Cluster) validateLibvirt() []error {
 	if c.Platform != PlatformLibvirt {
 		return errs
 	}
-	if err := validate.PrefixError("libvirt network ipRange", validate.SubnetCIDR(c.Libvirt.Network.IPRange)); err != nil {
+	if err := fieldError("libvirt.network.ipRange", c.Libvirt.Network.IPRange, validate.SubnetCIDR(c.Libvirt.Network.IPRange)); err != nil {
 		errs = append(errs, err)
 	}
 	if len(c.Libvirt.MasterIPs) > 0 {
 		if len(c.Libvirt.MasterIPs) != c.NodeCount(c.Master.NodePools) {
-			errs = append(errs, fmt.Errorf("length of masterIPs does't match master count"))
+			errs = append(errs, fieldError("libvirt.masterIPs", c.Libvirt.MasterIPs, fmt.Errorf("length of masterIPs does't match master count")))
 		}
 		for i, ip := range c.Libvirt.MasterIPs {
-			if err := validate.PrefixError(fmt.Sprintf("libvirt masterIPs[%d] %q", i, ip), validate.IPv4(ip)); err != nil {
+			if err := fieldError(fmt.Sprintf("libvirt.masterIPs[%d]", i), ip, validate.IPv4(ip)); err != nil {
 				errs = append(errs, err)
 			}
 		}
 	}
-	if err := validate.PrefixError("libvirt uri", validate.NonEmpty(c.Libvirt.URI)); err != nil {
+	if err := fieldError("libvirt.uri", c.Libvirt.URI, validate.NonEmpty(c.Libvirt.URI)); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("libvirt imagePath is not a valid QCOW image", validate.FileHeader(c.Libvirt.QCOWImagePath, qcowMagic)); err != nil {
+	if err := fieldError("libvirt.imagePath", c.Libvirt.QCOWImagePath, validate.PrefixError("not a valid QCOW image", validate.FileHeader(c.Libvirt.QCOWImagePath, qcowMagic))); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("libvirt sshKey", validate.NonEmpty(c.Libvirt.SSHKey)); err != nil {
+	if err := fieldError("libvirt.sshKey", c.Libvirt.SSHKey, validate.NonEmpty(c.Libvirt.SSHKey)); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("libvirt network name", validate.NonEmpty(c.Libvirt.Network.Name)); err != nil {
+	if err := fieldError("libvirt.network.name", c.Libvirt.Network.Name, validate.NonEmpty(c.Libvirt.Network.Name)); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("libvirt network ifName", validate.NonEmpty(c.Libvirt.Network.IfName)); err != nil {
+	if err := fieldError("libvirt.network.ifName", c.Libvirt.Network.IfName, validate.NonEmpty(c.Libvirt.Network.IfName)); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("libvirt network dnsServer", validate.IPv4(c.Libvirt.Network.DNSServer)); err != nil {
+	if err := fieldError("libvirt.network.dnsServer", c.Libvirt.Network.DNSServer, validate.IPv4(c.Libvirt.Network.DNSServer)); err != nil {
 		errs = append(errs, err)
 	}
-	errs = append(errs, c.validateOverlapWithPodOrServiceCIDR(c.Libvirt.Network.IPRange, "libvirt ipRange")...)
+	errs = append(errs, c.validateOverlapWithPodOrServiceCIDR(c.Libvirt.Network.IPRange, "libvirt.network.ipRange")...)
 	return errs
 }
 
 func (c *Cluster) validateNetworking() []error {
 	var errs []error
 	// https://en.wikipedia.org/wiki/Maximum_transmission_unit#MTUs_for_common_media
-	if err := validate.PrefixError("mtu", validate.IntRange(c.Networking.MTU, 68, 64*1024)); err != nil {
+	if err := fieldError("networking.mtu", c.Networking.MTU, validate.IntRange(c.Networking.MTU, 68, 64*1024)); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("podCIDR", validate.SubnetCIDR(c.Networking.PodCIDR)); err != nil {
+	if err := fieldError("networking.podCIDR", c.Networking.PodCIDR, validate.SubnetCIDR(c.Networking.PodCIDR)); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("serviceCIDR", validate.SubnetCIDR(c.Networking.ServiceCIDR)); err != nil {
+	if err := fieldError("networking.serviceCIDR", c.Networking.ServiceCIDR, validate.SubnetCIDR(c.Networking.ServiceCIDR)); err != nil {
 		errs = append(errs, err)
 	}
-	if err := c.validateNetworkType(); err != nil {
+	if err := fieldError("networking.type", c.Networking.Type, c.validateNetworkType()); err != nil {
 		errs = append(errs, err)
 	}
-	if err := validate.PrefixError("pod and service CIDRs", validate.CIDRsDontOverlap(c.Networking.PodCIDR, c.Networking.ServiceCIDR)); err != nil {
+	if err := fieldError("networking.serviceCIDR", c.Networking.ServiceCIDR, validate.PrefixError("networking.podCIDR", validate.CIDRsDontOverlap(c.Networking.PodCIDR, c.Networking.ServiceCIDR))); err != nil {
 		errs = append(errs, err)
 	}
 	return errs
@@ -235,11 +278,15 @@ func (c *Cluster) validateNetworkType() error {
 	}
 }
 
-// ValidateAndLog performs cluster configuration validation using `Validate`
-// but rather than return a slice of errors, it logs any errors and returns
-// a single error for convenience.
-func (c *Cluster) ValidateAndLog() error {
-	if errs := c.Validate(); len(errs) != 0 {
+// ValidateAndLog performs cluster configuration validation like `Validate`,
+// logging any errors and warnings. Besides the errors, it returns a single
+// error for convenience.
+func (c *Cluster) ValidateAndLog() ([]error, error) {
+	errs, warnings := c.validateBySeverity()
+	for _, w := range warnings {
+		log.Warnf("warning: %v", w)
+	}
+	if len(errs) != 0 {
 		s := ""
 		if len(errs) != 1 {
 			s = "s"
@@ -248,9 +295,9 @@ func (c *Cluster) ValidateAndLog() error {
 		for i, err := range errs {
 			log.Errorf("error %d: %v", i+1, err)
 		}
-		return fmt.Errorf("found %d cluster definition error%s", len(errs), s)
+		return errs, fmt.Errorf("found %d cluster definition error%s", len(errs), s)
 	}
-	return nil
+	return nil, nil
 }
 
 // validateAWSEndpoints ensures that the value of the endpoints field is one of:
@@ -283,20 +330,62 @@ func (c *Cluster) validateTNCS3Bucket() error {
 	return nil
 }
 
-func (c *Cluster) validateTectonicFiles() []error {
+// validateAdminPassword validates the admin password is either set or
+// referenced. The password is not reported as the value of its errors.
+func (c *Cluster) validateAdminPassword() []error {
 	var errs []error
-	if err := validate.JSONFile(c.PullSecretPath); err != nil {
-		errs = append(errs, err)
+	switch {
+	case c.Admin.Password != "" && c.Admin.PasswordFrom != nil:
+		errs = append(errs, fieldError("admin.password", nil, errors.New("cannot be set along with admin.passwordFrom")))
+	case c.Admin.PasswordFrom != nil:
+		if err := fieldError("admin.passwordFrom", nil, c.Admin.PasswordFrom.Validate()); err != nil {
+			errs = append(errs, err)
+		}
+	default:
+		if err := fieldError("admin.password", nil, validate.NonEmpty(c.Admin.Password)); err != nil {
+			errs = append(errs, err)
+		} else {
+			errs = append(errs, fieldWarning("admin.password", nil, errors.New("is stored in plain text, reference it with admin.passwordFrom instead")))
+		}
 	}
-	if err := validate.License(c.LicensePath); err != nil {
-		errs = append(errs, err)
+	return errs
+}
+
+func (c *Cluster) validateTectonicFiles() []error {
+	var errs []error
+	errs = append(errs, validateSecretFile("pullSecret", c.PullSecretPath, c.PullSecretFrom, validate.JSONFile)...)
+	errs = append(errs, validateSecretFile("license", c.LicensePath, c.LicenseFrom, validate.License)...)
+	return errs
+}
+
+// validateSecretFile validates a secret file, given either by the path of
+// the <field>Path field or by the reference of the <field>From field. The
+// content of referenced environment variables is only validated once
+// resolved.
+func validateSecretFile(field, path string, ref *SecretRef, validateFile func(string) error) []error {
+	var errs []error
+	switch {
+	case path != "" && ref != nil:
+		errs = append(errs, fieldError(field+"Path", path, fmt.Errorf("cannot be set along with %sFrom", field)))
+	case ref != nil:
+		err := ref.Validate()
+		if err == nil && ref.File != "" {
+			err = validateFile(ref.File)
+		}
+		if err := fieldError(field+"From", nil, err); err != nil {
+			errs = append(errs, err)
+		}
+	default:
+		if err := fieldError(field+"Path", path, validateFile(path)); err != nil {
+			errs = append(errs, err)
+		}
 	}
 	return errs
 }
 
 func (c *Cluster) validateIgnitionFiles() []error {
 	var errs []error
-	for _, n := range c.NodePools {
+	for i, n := range c.NodePools {
 		if n.IgnitionFile == "" {
 			continue
 		}
@@ -307,6 +396,9 @@ func (c *Cluster) validateIgnitionFiles() []error {
 		}
 
 		if err := validateIgnitionConfig(n.IgnitionFile); err != nil {
+			if e, ok := err.(*ErrInvalidIgnConfig); ok {
+				e.field = fmt.Sprintf("nodePools[%d].ignitionFile", i)
+			}
 			errs = append(errs, err)
 		}
 	}
@@ -322,8 +414,8 @@ func validateIgnitionConfig(filePath string) error {
 	_, rpt, _ := ignconfig.Parse(blob)
 	if len(rpt.Entries) > 0 {
 		return &ErrInvalidIgnConfig{
-			filePath,
-			rpt.String(),
+			filePath: filePath,
+			rpt:      rpt.String(),
 		}
 	}
 	return nil
@@ -332,12 +424,15 @@ func validateIgnitionConfig(filePath string) error {
 func (c *Cluster) validateNodePools() []error {
 	var errs []error
 	n := c.NodePools.Map()
+	// Every worker pool is a group of nodes of its own, while the etcd
+	// and master nodes form a single group.
 	fields := []struct {
-		pools []string
-		field string
+		pools    []string
+		field    string
+		multiple bool
 	}{
 		{pools: c.Master.NodePools, field: "master"},
-		{pools: c.Worker.NodePools, field: "worker"},
+		{pools: c.Worker.NodePools, field: "worker", multiple: true},
 		{pools: c.Etcd.NodePools, field: "etcd"},
 	}
 	for _, f := range fields {
@@ -348,19 +443,118 @@ func (c *Cluster) validateNodePools() []error {
 			}
 			found = true
 			if _, ok := n[p]; !ok {
-				errs = append(errs, &ErrUnmatchedNodePool{p})
+				errs = append(errs, &ErrUnmatchedNodePool{name: p, field: f.field})
 			}
 		}
 		if !found {
 			errs = append(errs, &ErrMissingNodePool{f.field})
 		}
-		if len(f.pools) > 1 {
+		if len(f.pools) > 1 && !f.multiple {
 			errs = append(errs, &ErrMoreThanOneNodePool{f.field})
 		}
 	}
 
+	// The names of the worker pools name their resources and files.
+	for i, p := range c.Worker.NodePools {
+		if p != "" && !workerPoolNameRegexp.MatchString(p) {
+			errs = append(errs, fieldError(fmt.Sprintf("worker.nodePools[%d]", i), p, errors.New("must be lower case alphanumeric characters or '-', and start and end with an alphanumeric character")))
+		}
+	}
+
 	errs = append(errs, c.validateNoSharedNodePools()...)
 
+	// etcd needs a majority of its members to be available, so an even
+	// number of members tolerates no more failures than one less.
+	if n := c.NodeCount(c.Etcd.NodePools); n > 0 && n%2 == 0 {
+		errs = append(errs, fieldWarning("etcd.nodePools", n, fmt.Errorf("%d etcd nodes tolerate as many failures as %d; use an odd number of nodes", n, n-1)))
+	}
+
+	return errs
+}
+
+// awsVolumeTypes are the EBS volume types of the root volumes of nodes.
+var awsVolumeTypes = []string{"gp2", "io1", "standard"}
+
+// roleVolumeType returns the AWS root volume type of the role using the node
+// pool of the given name, if any.
+func (c *Cluster) roleVolumeType(pool string) string {
+	for _, r := range c.roles() {
+		for _, p := range r.nodePools {
+			if p == pool {
+				return *r.volumeType
+			}
+		}
+	}
+	return ""
+}
+
+// validateNodePoolSettings validates the platform settings of the node
+// pools, which must match the platform of the cluster.
+func (c *Cluster) validateNodePoolSettings() []error {
+	var errs []error
+	for i, n := range c.NodePools {
+		field := fmt.Sprintf("nodePools[%d]", i)
+		if n.AWS != nil {
+			if c.Platform != PlatformAWS {
+				errs = append(errs, fieldError(field+".aws", nil, fmt.Errorf("is only supported on the %s platform", PlatformAWS)))
+			} else {
+				errs = append(errs, validateAWSNodePool(field+".aws", n.AWS, c.roleVolumeType(n.Name))...)
+			}
+		}
+		if n.Libvirt != nil {
+			if c.Platform != PlatformLibvirt {
+				errs = append(errs, fieldError(field+".libvirt", nil, fmt.Errorf("is only supported on the %s platform", PlatformLibvirt)))
+			} else {
+				errs = append(errs, validateLibvirtNodePool(field+".libvirt", n.Libvirt)...)
+			}
+		}
+	}
+	return errs
+}
+
+// validateAWSNodePool validates the AWS settings of a node pool. Its root
+// volume type defaults to roleVolumeType, the type of the role of the pool.
+func validateAWSNodePool(field string, p *aws.NodePool, roleVolumeType string) []error {
+	var errs []error
+	for i, id := range p.ExtraSGIDs {
+		if !strings.HasPrefix(id, "sg-") {
+			errs = append(errs, fieldError(fmt.Sprintf("%s.extraSGIDs[%d]", field, i), id, errors.New("is not a security group ID")))
+		}
+	}
+	v := p.RootVolume
+	if v.Type != "" {
+		found := false
+		for _, t := range awsVolumeTypes {
+			found = found || v.Type == t
+		}
+		if !found {
+			errs = append(errs, fieldError(field+".rootVolume.type", v.Type, fmt.Errorf("must be one of %s", awsVolumeTypes)))
+		}
+	}
+	if v.Size < 0 {
+		errs = append(errs, fieldError(field+".rootVolume.size", v.Size, errors.New("must be positive")))
+	}
+	volumeType := v.Type
+	if volumeType == "" {
+		volumeType = roleVolumeType
+	}
+	switch {
+	case v.IOPS < 0:
+		errs = append(errs, fieldError(field+".rootVolume.iops", v.IOPS, errors.New("must be positive")))
+	case v.IOPS > 0 && volumeType != "io1":
+		errs = append(errs, fieldError(field+".rootVolume.iops", v.IOPS, errors.New("is only supported for io1 volumes")))
+	}
+	return errs
+}
+
+func validateLibvirtNodePool(field string, p *libvirt.NodePool) []error {
+	var errs []error
+	if p.Memory != 0 && p.Memory < 512 {
+		errs = append(errs, fieldError(field+".memory", p.Memory, errors.New("must be at least 512 MiB")))
+	}
+	if p.VCPU < 0 {
+		errs = append(errs, fieldError(field+".vcpu", p.VCPU, errors.New("must be positive")))
+	}
 	return errs
 }
 
@@ -417,20 +611,20 @@ func (c *Cluster) validateCA() []error {
 
 	switch {
 	case (c.CA.RootCACertPath == "") != (c.CA.RootCAKeyPath == ""):
-		errs = append(errs, fmt.Errorf("rootCACertPath and rootCAKeyPath must both be set or empty"))
+		errs = append(errs, fieldError("CA", nil, fmt.Errorf("rootCACertPath and rootCAKeyPath must both be set or empty")))
 	case c.CA.RootCAKeyPath != "":
-		if err := validate.FileExists(c.CA.RootCAKeyPath); err != nil {
+		if err := fieldError("CA.rootCAKeyPath", c.CA.RootCAKeyPath, validate.FileExists(c.CA.RootCAKeyPath)); err != nil {
 			errs = append(errs, err)
 		}
-		if err := validateCAKey(c.CA.RootCAKeyPath); err != nil {
+		if err := fieldError("CA.rootCAKeyPath", c.CA.RootCAKeyPath, validateCAKey(c.CA.RootCAKeyPath)); err != nil {
 			errs = append(errs, err)
 		}
 		fallthrough
 	case c.CA.RootCACertPath != "":
-		if err := validate.FileExists(c.CA.RootCACertPath); err != nil {
+		if err := fieldError("CA.rootCACertPath", c.CA.RootCACertPath, validate.FileExists(c.CA.RootCACertPath)); err != nil {
 			errs = append(errs, err)
 		}
-		if err := validateCACert(c.CA.RootCACertPath); err != nil {
+		if err := fieldError("CA.rootCACertPath", c.CA.RootCACertPath, validateCACert(c.CA.RootCACertPath)); err != nil {
 			errs = append(errs, err)
 		}
 	}
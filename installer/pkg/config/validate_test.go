@@ -3,6 +3,7 @@ package config
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
 	"testing"
 
 	"github.com/coreos/tectonic-installer/installer/pkg/config/aws"
@@ -122,7 +123,7 @@ func TestMoreThanOneNodePool(t *testing.T) {
 					NodePools: []string{"etcd", "etcd2"},
 				},
 			},
-			errs: 3,
+			errs: 2,
 		},
 	}
 
@@ -336,6 +337,9 @@ func TestSharedNodePool(t *testing.T) {
 }
 
 func TestAWSEndpoints(t *testing.T) {
+	awsCluster := defaultCluster
+	awsCluster.Platform = PlatformAWS
+	awsCluster.applyPlatformDefaults()
 	cases := []struct {
 		cluster Cluster
 		err     bool
@@ -345,7 +349,7 @@ func TestAWSEndpoints(t *testing.T) {
 			err:     true,
 		},
 		{
-			cluster: defaultCluster,
+			cluster: awsCluster,
 			err:     false,
 		},
 		{
@@ -714,6 +718,7 @@ func TestValidateLibvirt(t *testing.T) {
 func TestValidateAWS(t *testing.T) {
 	d1 := defaultCluster
 	d1.Platform = PlatformAWS
+	d1.applyPlatformDefaults()
 	d2 := d1
 	d2.Name = "test"
 	d2.BaseDomain = "example.com"
@@ -820,3 +825,107 @@ func TestValidateOverlapWithPodOrServiceCIDR(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateNodePoolSettings(t *testing.T) {
+	cases := []struct {
+		name     string
+		platform Platform
+		pool     NodePool
+		// workerVolumeType is the root volume type of the workers, using
+		// the pool.
+		workerVolumeType string
+		fields           []string
+	}{
+		{
+			name:     "valid aws",
+			platform: PlatformAWS,
+			pool:     NodePool{AWS: &aws.NodePool{EC2Type: "r4.xlarge", ExtraSGIDs: []string{"sg-1"}, RootVolume: aws.RootVolume{IOPS: 100, Size: 60, Type: "io1"}}},
+		},
+		{
+			name:     "invalid aws",
+			platform: PlatformAWS,
+			pool:     NodePool{AWS: &aws.NodePool{ExtraSGIDs: []string{"vpc-1"}, RootVolume: aws.RootVolume{IOPS: 100, Size: -1, Type: "gp3"}}},
+			fields:   []string{"nodePools[0].aws.extraSGIDs[0]", "nodePools[0].aws.rootVolume.type", "nodePools[0].aws.rootVolume.size", "nodePools[0].aws.rootVolume.iops"},
+		},
+		{
+			name:             "aws iops of an io1 role",
+			platform:         PlatformAWS,
+			pool:             NodePool{AWS: &aws.NodePool{RootVolume: aws.RootVolume{IOPS: 1000}}},
+			workerVolumeType: "io1",
+		},
+		{
+			name:             "aws iops of a gp2 role",
+			platform:         PlatformAWS,
+			pool:             NodePool{AWS: &aws.NodePool{RootVolume: aws.RootVolume{IOPS: 1000}}},
+			workerVolumeType: "gp2",
+			fields:           []string{"nodePools[0].aws.rootVolume.iops"},
+		},
+		{
+			name:     "aws on libvirt",
+			platform: PlatformLibvirt,
+			pool:     NodePool{AWS: &aws.NodePool{EC2Type: "r4.xlarge"}},
+			fields:   []string{"nodePools[0].aws"},
+		},
+		{
+			name:     "valid libvirt",
+			platform: PlatformLibvirt,
+			pool:     NodePool{Libvirt: &libvirt.NodePool{Memory: 4096, VCPU: 2}},
+		},
+		{
+			name:     "invalid libvirt",
+			platform: PlatformLibvirt,
+			pool:     NodePool{Libvirt: &libvirt.NodePool{Memory: 256, VCPU: -1}},
+			fields:   []string{"nodePools[0].libvirt.memory", "nodePools[0].libvirt.vcpu"},
+		},
+		{
+			name:     "libvirt on aws",
+			platform: PlatformAWS,
+			pool:     NodePool{Libvirt: &libvirt.NodePool{Memory: 4096}},
+			fields:   []string{"nodePools[0].libvirt"},
+		},
+	}
+
+	for _, c := range cases {
+		c.pool.Name = "worker"
+		cluster := Cluster{Platform: c.platform, NodePools: NodePools{c.pool}, Worker: Worker{NodePools: []string{"worker"}}}
+		cluster.AWS.Worker.WorkerRootVolume.Type = c.workerVolumeType
+		var fields []string
+		for _, err := range cluster.validateNodePoolSettings() {
+			fields = append(fields, err.(*ValidationError).Field)
+		}
+		if !reflect.DeepEqual(fields, c.fields) {
+			t.Errorf("test case %s: expected errors of %v, got %v", c.name, c.fields, fields)
+		}
+	}
+}
+
+func TestValidateWorkerPoolNames(t *testing.T) {
+	cases := []struct {
+		pools  []string
+		fields []string
+	}{
+		{
+			pools: []string{"worker", "gpu-2"},
+		},
+		{
+			pools:  []string{"worker", "GPU", "-gpu", "gpu_2"},
+			fields: []string{"worker.nodePools[1]", "worker.nodePools[2]", "worker.nodePools[3]"},
+		},
+	}
+
+	for i, c := range cases {
+		cluster := Cluster{Worker: Worker{NodePools: c.pools}}
+		for _, p := range c.pools {
+			cluster.NodePools = append(cluster.NodePools, NodePool{Name: p, Count: 1})
+		}
+		var fields []string
+		for _, err := range cluster.validateNodePools() {
+			if err, ok := err.(*ValidationError); ok {
+				fields = append(fields, err.Field)
+			}
+		}
+		if !reflect.DeepEqual(fields, c.fields) {
+			t.Errorf("test case %d: expected errors of %v, got %v", i, c.fields, fields)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"strings"
+)
+
+// Severity is the severity of a validation error.
+type Severity string
+
+const (
+	// SeverityError is the severity of the errors making a config invalid.
+	SeverityError Severity = "error"
+	// SeverityWarning is the severity of the issues of a valid config.
+	SeverityWarning Severity = "warning"
+)
+
+// ValidationError is an issue of a field of the config.
+type ValidationError struct {
+	// Field is the YAML path of the field, e.g. "aws.master.rootVolume.iops",
+	// or empty if the issue is not about a single field.
+	Field string `json:"field,omitempty"`
+	// Value is the offending value of the field, if it can be reported.
+	Value interface{} `json:"value,omitempty"`
+	// Line is the line of the field in the config file, if it is known.
+	Line     int      `json:"line,omitempty"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// ValidationError implements the error interface.
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return e.Field + ": " + e.Message
+}
+
+// fieldPather is implemented by the validation errors of a single field
+// which are not ValidationErrors.
+type fieldPather interface {
+	fieldPath() string
+}
+
+// fieldError returns a ValidationError of the field at the given path for
+// err, or nil if err is nil.
+func fieldError(field string, value interface{}, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ValidationError{
+		Field:    field,
+		Value:    value,
+		Severity: SeverityError,
+		Message:  err.Error(),
+	}
+}
+
+// fieldWarning is like fieldError, for issues which do not make the config
+// invalid.
+func fieldWarning(field string, value interface{}, err error) error {
+	e := fieldError(field, value, err)
+	if e != nil {
+		e.(*ValidationError).Severity = SeverityWarning
+	}
+	return e
+}
+
+// Check returns the errors and warnings of the Cluster as ValidationErrors.
+// The lines of their fields are looked up in source, the config file the
+// Cluster was parsed from, if given.
+func (c *Cluster) Check(source []byte) []*ValidationError {
+	var lines []string
+	if source != nil {
+		lines = strings.Split(string(source), "\n")
+	}
+
+	var errs []*ValidationError
+	for _, err := range c.validate() {
+		errs = append(errs, toValidationError(err, lines))
+	}
+	return errs
+}
+
+// ParseErrors returns the ValidationErrors of an error returned by
+// ParseConfig. Errors other than unknown fields have no field.
+func ParseErrors(err error) []*ValidationError {
+	unknown, ok := err.(ErrUnknownFields)
+	if !ok {
+		return []*ValidationError{{Severity: SeverityError, Message: err.Error()}}
+	}
+
+	var errs []*ValidationError
+	for _, f := range unknown {
+		e := &ValidationError{
+			Field:    f.Path,
+			Line:     f.Line,
+			Severity: SeverityError,
+			Message:  "unknown field",
+		}
+		if f.Suggestion != "" {
+			e.Message += ", did you mean \"" + f.Suggestion + "\"?"
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+func toValidationError(err error, lines []string) *ValidationError {
+	e, ok := err.(*ValidationError)
+	if !ok {
+		e = &ValidationError{Severity: SeverityError, Message: err.Error()}
+		if f, ok := err.(fieldPather); ok {
+			e.Field = f.fieldPath()
+		}
+	}
+	if e.Field != "" && lines != nil {
+		e.Line = findKeyLine(lines, e.Field)
+	}
+	return e
+}
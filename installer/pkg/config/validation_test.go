@@ -0,0 +1,61 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	source := []byte(`name: test
+platform: aws
+aws:
+  region: eu-west-1
+  vpcCIDRBlock: 10.0.0.0/33
+etcd:
+  nodePools:
+    - etcd
+nodePools:
+  - name: etcd
+    count: 2
+`)
+	cluster, err := ParseConfig(source)
+	if err != nil {
+		t.Fatalf("failed to parse config: %v", err)
+	}
+
+	cases := []struct {
+		field    string
+		line     int
+		severity Severity
+	}{
+		{field: "aws.vpcCIDRBlock", line: 5, severity: SeverityError},
+		{field: "etcd.nodePools", line: 7, severity: SeverityWarning},
+		{field: "master.nodePools", line: 0, severity: SeverityError},
+	}
+
+	errs := cluster.Check(source)
+	for _, c := range cases {
+		var found *ValidationError
+		for _, e := range errs {
+			if e.Field == c.field {
+				found = e
+				break
+			}
+		}
+		if found == nil {
+			t.Errorf("test case %s: expected a validation error, got %v", c.field, errs)
+			continue
+		}
+		if found.Line != c.line {
+			t.Errorf("test case %s: expected line %d, got %d", c.field, c.line, found.Line)
+		}
+		if found.Severity != c.severity {
+			t.Errorf("test case %s: expected severity %s, got %s", c.field, c.severity, found.Severity)
+		}
+	}
+
+	for _, err := range cluster.Validate() {
+		if e, ok := err.(*ValidationError); ok && e.Severity == SeverityWarning {
+			t.Errorf("expected Validate not to return warnings, got %v", e)
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package wizard
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+	"github.com/coreos/tectonic-installer/installer/pkg/config/aws"
+	"github.com/coreos/tectonic-installer/installer/pkg/validate"
+)
+
+// question is a setting of the cluster config the wizard asks for.
+type question struct {
+	// key identifies the question in the answers and the config template.
+	key    string
+	prompt string
+	// def is the answer used when none is given, if any.
+	def string
+	// platform, when set, restricts the question to the given platform.
+	platform config.Platform
+	validate func(string) error
+}
+
+var questions = []question{
+	{key: "platform", prompt: "Platform (aws, libvirt)", def: string(config.PlatformAWS), validate: validatePlatform},
+	{key: "name", prompt: "Cluster name", platform: config.PlatformAWS, validate: validate.AWSClusterName},
+	{key: "name", prompt: "Cluster name", platform: config.PlatformLibvirt, validate: validate.ClusterName},
+	{key: "baseDomain", prompt: "Base DNS domain", validate: validate.DomainName},
+	{key: "adminEmail", prompt: "Admin e-mail address", validate: validate.Email},
+	{key: "licensePath", prompt: "Path of the Tectonic license file", validate: validate.License},
+	{key: "pullSecretPath", prompt: "Path of the pull secret file", validate: validate.JSONFile},
+
+	{key: "aws.region", prompt: "AWS region", def: aws.DefaultRegion, platform: config.PlatformAWS, validate: validate.NonEmpty},
+	{key: "aws.profile", prompt: "AWS credentials profile", def: aws.DefaultProfile, platform: config.PlatformAWS, validate: validate.NonEmpty},
+	{key: "aws.sshKey", prompt: "Name of the AWS SSH key pair", platform: config.PlatformAWS, validate: validate.NonEmpty},
+	{key: "aws.vpcCIDRBlock", prompt: "CIDR block of the VPC", def: aws.DefaultVPCCIDRBlock, platform: config.PlatformAWS, validate: validate.SubnetCIDR},
+
+	{key: "libvirt.uri", prompt: "libvirt URI", def: "qemu:///system", platform: config.PlatformLibvirt, validate: validate.NonEmpty},
+	{key: "libvirt.sshKey", prompt: "SSH public key", platform: config.PlatformLibvirt, validate: validate.OpenSSHPublicKey},
+	{key: "libvirt.imagePath", prompt: "Path of the Container Linux QCOW image", platform: config.PlatformLibvirt, validate: validateQCOWImage},
+	{key: "libvirt.ipRange", prompt: "IP range of the libvirt network", def: "192.168.124.0/24", platform: config.PlatformLibvirt, validate: validate.SubnetCIDR},
+
+	{key: "etcdCount", prompt: "Number of etcd nodes", def: "3", validate: validateEtcdCount},
+	{key: "masterCount", prompt: "Number of master nodes", def: "1", validate: func(v string) error { return validate.IntRange(v, 1, 100) }},
+	{key: "workerCount", prompt: "Number of worker nodes", def: "2", validate: func(v string) error { return validate.IntRange(v, 1, 1000) }},
+}
+
+func validatePlatform(v string) error {
+	switch config.Platform(v) {
+	case config.PlatformAWS, config.PlatformLibvirt:
+		return nil
+	}
+	return fmt.Errorf("must be one of %s", []config.Platform{config.PlatformAWS, config.PlatformLibvirt})
+}
+
+func validateQCOWImage(v string) error {
+	return validate.PrefixError("not a valid QCOW image", validate.FileHeader(v, []byte{'Q', 'F', 'I', 0xfb}))
+}
+
+func validateEtcdCount(v string) error {
+	if err := validate.IntRange(v, 1, 9); err != nil {
+		return err
+	}
+	return validate.IntOdd(v)
+}
+
+// Wizard asks the questions of the cluster config.
+type Wizard struct {
+	in  *bufio.Reader
+	out io.Writer
+	// answers, when set, holds the answers to the questions by key, instead
+	// of asking them.
+	answers map[string]string
+}
+
+// New returns a Wizard asking the questions on out and reading the answers
+// from in. Invalid answers are explained and the question asked again.
+func New(in io.Reader, out io.Writer) *Wizard {
+	return &Wizard{in: bufio.NewReader(in), out: out}
+}
+
+// NewFromAnswersFile returns a Wizard answering the questions from the
+// YAML file at the given path, mapping the keys of the questions to their
+// answers. Questions without an answer get their default one, and keys
+// matching no question are rejected.
+func NewFromAnswersFile(path string) (*Wizard, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	answers := make(map[string]string)
+	if err := yaml.Unmarshal(data, &answers); err != nil {
+		return nil, fmt.Errorf("%s is not a valid answers file: %v", path, err)
+	}
+
+	known := make(map[string]bool)
+	var keys []string
+	for _, q := range questions {
+		if !known[q.key] {
+			known[q.key] = true
+			keys = append(keys, q.key)
+		}
+	}
+	var unknown []string
+	for key := range answers {
+		if !known[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%s is not a valid answers file: unknown keys %s, expected some of: %s", path, strings.Join(unknown, ", "), strings.Join(keys, ", "))
+	}
+	return &Wizard{answers: answers}, nil
+}
+
+// Run asks the questions and returns the commented YAML cluster config
+// built from the answers.
+func (w *Wizard) Run() ([]byte, error) {
+	answers := make(map[string]string)
+	for _, q := range questions {
+		if q.platform != "" && config.Platform(answers["platform"]) != q.platform {
+			continue
+		}
+		answer, err := w.ask(q)
+		if err != nil {
+			return nil, err
+		}
+		answers[q.key] = answer
+	}
+
+	var buf bytes.Buffer
+	if err := configTemplate.Execute(&buf, answers); err != nil {
+		return nil, fmt.Errorf("failed to render the config: %v", err)
+	}
+	// The config is parsed to make sure it is valid YAML for the current
+	// schema. Its settings are validated when the cluster is initialized.
+	if _, err := config.ParseConfig(buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to build the config: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// ask returns the valid answer to the given question.
+func (w *Wizard) ask(q question) (string, error) {
+	if w.answers != nil {
+		answer, ok := w.answers[q.key]
+		if !ok {
+			answer = q.def
+		}
+		if err := q.validate(answer); err != nil {
+			return "", fmt.Errorf("invalid answer to %s (%s): %v", q.key, q.prompt, err)
+		}
+		return answer, nil
+	}
+
+	for {
+		if q.def != "" {
+			fmt.Fprintf(w.out, "%s [%s]: ", q.prompt, q.def)
+		} else {
+			fmt.Fprintf(w.out, "%s: ", q.prompt)
+		}
+		line, err := w.in.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", fmt.Errorf("failed to read the answer to %s: %v", q.key, err)
+		}
+		answer := strings.TrimSpace(line)
+		if answer == "" {
+			answer = q.def
+		}
+		if err := q.validate(answer); err != nil {
+			fmt.Fprintf(w.out, "Invalid answer: %v\n", err)
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// quote returns the given string as a YAML scalar.
+func quote(s string) (string, error) {
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+var configTemplate = template.Must(template.New("config").Funcs(template.FuncMap{"quote": quote}).Parse(`# The config of the Tectonic cluster, written by 'tectonic init --interactive'.
+# See examples/tectonic.{{ .platform }}.yaml for the settings left out.
+apiVersion: ` + config.APIVersion + `
+kind: ` + config.Kind + `
+
+# The name of the cluster, used as the prefix of its resources and as the
+# subdomain of its DNS records.
+name: {{ quote .name }}
+
+# The base DNS domain of the cluster.
+baseDomain: {{ quote .baseDomain }}
+
+# The platform the cluster runs on.
+platform: {{ .platform }}
+
+admin:
+  # The e-mail address of the admin user of the Tectonic Console.
+  email: {{ quote .adminEmail }}
+  # Without a password, 'tectonic init' generates one, stored in clear text
+  # in the admin-password file of the cluster directory. Reference your own
+  # with passwordFrom, e.g.:
+  #
+  # passwordFrom:
+  #   env: TECTONIC_ADMIN_PASSWORD
+
+# The path to the Tectonic license file.
+licensePath: {{ quote .licensePath }}
+
+# The path to the pull secret file holding the credentials of the registry
+# of the Tectonic container images.
+pullSecretPath: {{ quote .pullSecretPath }}
+{{ if eq .platform "aws" }}
+aws:
+  # The target AWS region for the cluster.
+  region: {{ quote (index . "aws.region") }}
+  # The AWS credentials profile, in ~/.aws/credentials.
+  profile: {{ quote (index . "aws.profile") }}
+  # The name of an SSH key pair of the AWS region.
+  sshKey: {{ quote (index . "aws.sshKey") }}
+  # The CIDR block of the VPC of the cluster.
+  vpcCIDRBlock: {{ quote (index . "aws.vpcCIDRBlock") }}
+{{ else }}
+libvirt:
+  # The URI of the libvirt daemon.
+  uri: {{ quote (index . "libvirt.uri") }}
+  # The SSH public key authorized on the nodes.
+  sshKey: {{ quote (index . "libvirt.sshKey") }}
+  # The path of the Container Linux QCOW image of the nodes.
+  imagePath: {{ quote (index . "libvirt.imagePath") }}
+  network:
+    name: tectonic
+    ifName: tt0
+    dnsServer: 8.8.8.8
+    # The IP range of the network of the nodes.
+    ipRange: {{ quote (index . "libvirt.ipRange") }}
+{{ end }}
+# The node pools of the etcd, master and worker nodes.
+etcd:
+  nodePools:
+    - etcd
+master:
+  nodePools:
+    - master
+worker:
+  nodePools:
+    - worker
+nodePools:
+  - name: etcd
+    count: {{ .etcdCount }}
+  - name: master
+    count: {{ .masterCount }}
+  - name: worker
+    count: {{ .workerCount }}
+`))
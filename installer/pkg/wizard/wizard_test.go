@@ -0,0 +1,180 @@
+package wizard
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	jose "gopkg.in/square/go-jose.v2"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+// writeLicense writes a license expiring in a year in the given directory
+// and returns its path.
+func writeLicense(t *testing.T, dir string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to create RSA key pair: %v", err)
+	}
+	s, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.RS256, Key: key}, nil)
+	if err != nil {
+		t.Fatalf("failed to create license signer: %v", err)
+	}
+	claims, err := json.Marshal(struct {
+		ExpirationDate time.Time `json:"expirationDate"`
+	}{time.Now().AddDate(1, 0, 0)})
+	if err != nil {
+		t.Fatalf("failed to marshal license: %v", err)
+	}
+	jws, err := s.Sign(claims)
+	if err != nil {
+		t.Fatalf("failed to sign license: %v", err)
+	}
+	license, err := jws.CompactSerialize()
+	if err != nil {
+		t.Fatalf("failed to serialize license: %v", err)
+	}
+	path := filepath.Join(dir, "license.txt")
+	if err := ioutil.WriteFile(path, []byte(license), 0644); err != nil {
+		t.Fatalf("failed to write license: %v", err)
+	}
+	return path
+}
+
+func TestWizard(t *testing.T) {
+	dir, err := ioutil.TempDir("", "wizard")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	license := writeLicense(t, dir)
+	pullSecret := filepath.Join(dir, "pull-secret.json")
+	if err := ioutil.WriteFile(pullSecret, []byte("{}"), 0644); err != nil {
+		t.Fatalf("failed to write pull secret: %v", err)
+	}
+	image := filepath.Join(dir, "coreos.qcow")
+	if err := ioutil.WriteFile(image, []byte("QFI\xfb"), 0644); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+	answersData := fmt.Sprintf(`platform: aws
+name: test
+baseDomain: example.com
+adminEmail: admin@example.com
+licensePath: %s
+pullSecretPath: %s
+aws.sshKey: core
+workerCount: 4
+`, license, pullSecret)
+	answers := filepath.Join(dir, "answers.yaml")
+	if err := ioutil.WriteFile(answers, []byte(answersData), 0644); err != nil {
+		t.Fatalf("failed to write answers: %v", err)
+	}
+	invalidAnswers := filepath.Join(dir, "invalid-answers.yaml")
+	if err := ioutil.WriteFile(invalidAnswers, []byte("platform: gce\n"), 0644); err != nil {
+		t.Fatalf("failed to write answers: %v", err)
+	}
+	// Dots are not allowed in the names of AWS clusters.
+	invalidAWSName := filepath.Join(dir, "invalid-aws-name.yaml")
+	if err := ioutil.WriteFile(invalidAWSName, []byte(strings.Replace(answersData, "name: test", "name: my.cluster", 1)), 0644); err != nil {
+		t.Fatalf("failed to write answers: %v", err)
+	}
+	unknownAnswers := filepath.Join(dir, "unknown-answers.yaml")
+	if err := ioutil.WriteFile(unknownAnswers, []byte("platform: aws\nworkerCont: 4\n"), 0644); err != nil {
+		t.Fatalf("failed to write answers: %v", err)
+	}
+	if _, err := NewFromAnswersFile(unknownAnswers); err == nil || !strings.Contains(err.Error(), "workerCont") {
+		t.Errorf("expected the unknown key of the answers to be rejected, got %v", err)
+	}
+
+	fromFile, err := NewFromAnswersFile(answers)
+	if err != nil {
+		t.Fatalf("failed to read answers: %v", err)
+	}
+	invalid, err := NewFromAnswersFile(invalidAnswers)
+	if err != nil {
+		t.Fatalf("failed to read answers: %v", err)
+	}
+	invalidName, err := NewFromAnswersFile(invalidAWSName)
+	if err != nil {
+		t.Fatalf("failed to read answers: %v", err)
+	}
+	var prompts bytes.Buffer
+	// The invalid name, image and etcd count are asked again.
+	input := strings.Join([]string{"libvirt", "-invalid-", "test", "example.com", "admin@example.com", license, pullSecret, "", "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAABAQC7 core", license, image, "", "2", "", "", ""}, "\n") + "\n"
+	interactive := New(strings.NewReader(input), &prompts)
+
+	cases := []struct {
+		name     string
+		wizard   *Wizard
+		expected func(c *config.Cluster) bool
+		err      bool
+	}{
+		{
+			name:   "answers file",
+			wizard: fromFile,
+			expected: func(c *config.Cluster) bool {
+				return c.Platform == config.PlatformAWS && c.AWS.Region == "eu-west-1" && c.AWS.SSHKey == "core" &&
+					c.NodeCount([]string{"etcd"}) == 3 && c.NodeCount([]string{"worker"}) == 4
+			},
+		},
+		{
+			name:   "interactive",
+			wizard: interactive,
+			expected: func(c *config.Cluster) bool {
+				return c.Platform == config.PlatformLibvirt && c.Name == "test" && c.Libvirt.URI == "qemu:///system" &&
+					c.Libvirt.QCOWImagePath == image && c.NodeCount([]string{"etcd"}) == 3
+			},
+		},
+		{
+			name:   "invalid answer",
+			wizard: invalid,
+			err:    true,
+		},
+		{
+			name:   "invalid AWS cluster name",
+			wizard: invalidName,
+			err:    true,
+		},
+	}
+
+	for _, c := range cases {
+		data, err := c.wizard.Run()
+		if c.err {
+			if err == nil {
+				t.Errorf("test case %s: expected an error", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test case %s: unexpected error: %v", c.name, err)
+			continue
+		}
+		cluster, err := config.ParseConfig(data)
+		if err != nil {
+			t.Errorf("test case %s: failed to parse the config: %v", c.name, err)
+			continue
+		}
+		// The admin password is generated by init.
+		cluster.Admin.PasswordFrom = &config.SecretRef{Env: "TECTONIC_ADMIN_PASSWORD"}
+		if errs := cluster.Validate(); len(errs) != 0 {
+			t.Errorf("test case %s: expected a valid config, got %v", c.name, errs)
+		}
+		if !c.expected(cluster) {
+			t.Errorf("test case %s: unexpected config:\n%s", c.name, data)
+		}
+	}
+
+	if n := strings.Count(prompts.String(), "Invalid answer"); n != 3 {
+		t.Errorf("expected 3 invalid answers, got %d:\n%s", n, prompts.String())
+	}
+}
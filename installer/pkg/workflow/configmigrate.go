@@ -0,0 +1,60 @@
+package workflow
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+// ConfigMigrateWorkflow creates new instances of the 'config migrate'
+// workflow, responsible for upgrading a cluster config to the current
+// version of the config schema. The config file is rewritten in place,
+// after being backed up next to it.
+func ConfigMigrateWorkflow(configFilePath string) Workflow {
+	return Workflow{
+		metadata: metadata{configFilePath: configFilePath},
+		steps: []Step{
+			migrateConfigFileStep,
+		},
+	}
+}
+
+func migrateConfigFileStep(m *metadata) error {
+	info, err := os.Stat(m.configFilePath)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(m.configFilePath)
+	if err != nil {
+		return err
+	}
+
+	version, migrated, err := config.Migrate(data)
+	if err != nil {
+		return fmt.Errorf("%s cannot be migrated: %v", m.configFilePath, err)
+	}
+	if version == config.APIVersion {
+		log.Infof("%s already has the current apiVersion %q", m.configFilePath, config.APIVersion)
+		return nil
+	}
+	// Make sure the upgraded config is valid YAML for the current schema
+	// before replacing the file.
+	if _, err := config.ParseConfig(migrated); err != nil {
+		return fmt.Errorf("%s was migrated to an invalid config: %v", m.configFilePath, err)
+	}
+
+	backupPath := m.configFilePath + ".bak"
+	if err := ioutil.WriteFile(backupPath, data, info.Mode()); err != nil {
+		return fmt.Errorf("failed to back up %s: %v", m.configFilePath, err)
+	}
+	if err := ioutil.WriteFile(m.configFilePath, migrated, info.Mode()); err != nil {
+		return fmt.Errorf("failed to write migrated config, the original is backed up at %s: %v", backupPath, err)
+	}
+
+	log.Infof("Migrated %s from apiVersion %q to %q, the original is backed up at %s", m.configFilePath, version, config.APIVersion, backupPath)
+	return nil
+}
@@ -0,0 +1,104 @@
+package workflow
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+const (
+	configRenderWorkflow = "config-render"
+	// hiddenSecret replaces the secrets of the rendered configs.
+	hiddenSecret = "(hidden)"
+)
+
+// ConfigRenderWorkflow creates new instances of the 'config render'
+// workflow, responsible for printing the effective config of a cluster,
+// with its defaults and derived values, as YAML or as the terraform.tfvars
+// the install applies. The config is read from the given config files,
+// merged in order, or else from the cluster directory. The admin password
+// is hidden unless showSecrets is set.
+func ConfigRenderWorkflow(clusterDir string, configFilePaths []string, tfvars, showSecrets bool) Workflow {
+	m := metadata{clusterDir: clusterDir}
+	if len(configFilePaths) > 0 {
+		m.configFilePath = configFilePaths[0]
+		m.configOverlays = configFilePaths[1:]
+	}
+	steps := []Step{loadRenderedConfigStep}
+	if !showSecrets {
+		steps = append(steps, hideSecretsStep)
+	}
+	if tfvars {
+		steps = append(steps, printRenderedTFVarsStep)
+	} else {
+		steps = append(steps, printRenderedYAMLStep)
+	}
+	return Workflow{
+		name:     configRenderWorkflow,
+		readOnly: true,
+		metadata: m,
+		steps:    steps,
+	}
+}
+
+// loadRenderedConfigStep reads the config files of the metadata, if any,
+// or else the config of the cluster directory, into the metadata.
+func loadRenderedConfigStep(m *metadata) error {
+	if m.configFilePath == "" {
+		return loadClusterConfigStep(m)
+	}
+
+	paths := append([]string{m.configFilePath}, m.configOverlays...)
+	merged, err := config.MergeConfigFiles(paths...)
+	if err != nil {
+		return err
+	}
+	if merged, err = config.InterpolateConfig(merged, ""); err != nil {
+		return err
+	}
+	cluster, err := config.ParseConfig(merged)
+	if err != nil {
+		return err
+	}
+	m.cluster = *cluster
+	return nil
+}
+
+// hideSecretsStep replaces the admin password of the config of the metadata,
+// so that it is not printed.
+func hideSecretsStep(m *metadata) error {
+	if m.cluster.Admin.Password != "" {
+		m.cluster.Admin.Password = hiddenSecret
+	}
+	return nil
+}
+
+func printRenderedYAMLStep(m *metadata) error {
+	if err := m.cluster.Derive(); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(m.cluster)
+	if err != nil {
+		return err
+	}
+
+	// The derived node counts have no field in the config.
+	out := m.output()
+	fmt.Fprintln(out, "# The effective config, rendered by 'tectonic config render'.")
+	fmt.Fprintf(out, "# Derived node counts: etcd %d, master %d, worker %d.\n", m.cluster.Etcd.Count, m.cluster.Master.Count, m.cluster.Worker.Count)
+	_, err = fmt.Fprint(out, string(data))
+	return err
+}
+
+// printRenderedTFVarsStep prints the variables of the config as the install
+// writes them to terraform.tfvars.
+func printRenderedTFVarsStep(m *metadata) error {
+	vars, err := terraformVariables(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(m.output(), vars)
+	return err
+}
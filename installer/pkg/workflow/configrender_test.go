@@ -0,0 +1,80 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigRenderWorkflowTFVars(t *testing.T) {
+	clusterDir, err := ioutil.TempDir("", "configrender")
+	if err != nil {
+		t.Fatalf("failed to create cluster dir: %v", err)
+	}
+	defer os.RemoveAll(clusterDir)
+
+	files := map[string]string{
+		internalFileName: "",
+		configFileName: `admin:
+  email: fake-email@example.com
+  password: secret-password
+platform: libvirt
+libvirt: {network: {ipRange: 192.168.124.0/24}}
+etcd:
+  nodePools: [etcd]
+master:
+  nodePools: [master]
+worker:
+  nodePools: [worker, gpu]
+nodePools:
+  - {name: etcd, count: 1}
+  - {name: master, count: 1}
+  - {name: worker, count: 1}
+  - {name: gpu, count: 2}
+`,
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(clusterDir, name), []byte(data), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	domains := make(map[string]tfStateResource)
+	for i, name := range []string{"worker0", "worker-gpu-0"} {
+		domains[fmt.Sprintf("libvirt_domain.worker.%d", i)] = testResource("libvirt_domain", name, map[string]string{"name": name})
+	}
+	writeTestState(t, clusterDir, joinWorkersStep, domains)
+
+	m := &metadata{clusterDir: clusterDir}
+	if err := loadClusterConfigStep(m); err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+	if err := generateTerraformVariablesStep(m); err != nil {
+		t.Fatalf("failed to generate the tfvars: %v", err)
+	}
+	applied, err := ioutil.ReadFile(filepath.Join(clusterDir, terraformVariablesFileName))
+	if err != nil {
+		t.Fatalf("failed to read the tfvars: %v", err)
+	}
+
+	for _, showSecrets := range []bool{false, true} {
+		var out bytes.Buffer
+		w := ConfigRenderWorkflow(clusterDir, nil, true, showSecrets)
+		w.metadata.out = &out
+		if err := w.Execute(context.Background()); err != nil {
+			t.Fatalf("failed to render the tfvars: %v", err)
+		}
+
+		expected := string(applied)
+		if !showSecrets {
+			expected = strings.Replace(expected, "secret-password", hiddenSecret, 1)
+		}
+		if out.String() != expected {
+			t.Errorf("expected the rendered tfvars with showSecrets %t to be:\n%s\ngot:\n%s", showSecrets, expected, out.String())
+		}
+	}
+}
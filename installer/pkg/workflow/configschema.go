@@ -0,0 +1,29 @@
+package workflow
+
+import (
+	"fmt"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+// ConfigSchemaWorkflow creates new instances of the 'config schema'
+// workflow, responsible for printing the JSON Schema of the cluster config,
+// for editors supporting YAML language servers to complete and check
+// config files.
+func ConfigSchemaWorkflow() Workflow {
+	return Workflow{
+		readOnly: true,
+		steps: []Step{
+			printConfigSchemaStep,
+		},
+	}
+}
+
+func printConfigSchemaStep(m *metadata) error {
+	schema, err := config.JSONSchema()
+	if err != nil {
+		return fmt.Errorf("failed to generate the config schema: %v", err)
+	}
+	_, err = fmt.Fprintln(m.output(), string(schema))
+	return err
+}
@@ -0,0 +1,108 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+const configValidateWorkflow = "config-validate"
+
+// validationReport is the outcome of validating a config file.
+type validationReport struct {
+	Config string `json:"config"`
+	Valid  bool   `json:"valid"`
+	// Errors lists the errors and warnings of the config.
+	Errors []*config.ValidationError `json:"errors"`
+}
+
+// ConfigValidateWorkflow creates new instances of the 'config validate'
+// workflow, responsible for reporting the errors and warnings of a config
+// file, with the path and line of their fields. The workflow fails if the
+// config has errors.
+func ConfigValidateWorkflow(configFilePath string, jsonOutput bool) Workflow {
+	printStep := printValidationStep
+	if jsonOutput {
+		printStep = printValidationJSONStep
+	}
+	return Workflow{
+		name:     configValidateWorkflow,
+		readOnly: true,
+		metadata: metadata{configFilePath: configFilePath},
+		steps: []Step{
+			printStep,
+		},
+	}
+}
+
+// validateConfigFile parses and validates the config file of the metadata.
+func validateConfigFile(m *metadata) (*validationReport, error) {
+	data, err := ioutil.ReadFile(m.configFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &validationReport{Config: m.configFilePath, Valid: true, Errors: []*config.ValidationError{}}
+	if cluster, err := config.ParseConfigFile(m.configFilePath); err != nil {
+		r.Errors = config.ParseErrors(err)
+	} else {
+		r.Errors = append(r.Errors, cluster.Check(data)...)
+	}
+	for _, e := range r.Errors {
+		if e.Severity == config.SeverityError {
+			r.Valid = false
+		}
+	}
+	return r, nil
+}
+
+// validationResult returns the error of the workflow for the given report.
+func validationResult(r *validationReport) error {
+	if r.Valid {
+		return nil
+	}
+	return fmt.Errorf("%s is not a valid config", r.Config)
+}
+
+func printValidationJSONStep(m *metadata) error {
+	r, err := validateConfigFile(m)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(m.output(), string(data)); err != nil {
+		return err
+	}
+
+	return validationResult(r)
+}
+
+func printValidationStep(m *metadata) error {
+	r, err := validateConfigFile(m)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range r.Errors {
+		location := r.Config
+		if e.Line > 0 {
+			location = fmt.Sprintf("%s:%d", location, e.Line)
+		}
+		value := ""
+		if v := fmt.Sprint(e.Value); e.Value != nil && v != "" {
+			value = fmt.Sprintf(" (value: %s)", v)
+		}
+		fmt.Fprintf(m.output(), "%s: %s: %v%s\n", location, e.Severity, e, value)
+	}
+	if r.Valid {
+		fmt.Fprintf(m.output(), "%s is valid\n", r.Config)
+	}
+
+	return validationResult(r)
+}
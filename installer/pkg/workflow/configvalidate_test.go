@@ -0,0 +1,36 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigValidateWorkflowOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "configvalidate")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	configFilePath := filepath.Join(dir, configFileName)
+	if err := ioutil.WriteFile(configFilePath, []byte("name: test\niscsi:\n  enabled: false\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	for _, jsonOutput := range []bool{false, true} {
+		var out bytes.Buffer
+		w := ConfigValidateWorkflow(configFilePath, jsonOutput)
+		w.metadata.out = &out
+		if err := w.Execute(context.Background()); err == nil {
+			t.Errorf("json %t: expected the config to be invalid", jsonOutput)
+		}
+		if !strings.Contains(out.String(), "iscsi") {
+			t.Errorf("json %t: expected the report to be written to the workflow output, got %q", jsonOutput, out.String())
+		}
+	}
+}
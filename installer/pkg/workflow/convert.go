@@ -1,34 +1,63 @@
 package workflow
 
 import (
-	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
 
 	"github.com/coreos/tectonic-installer/installer/pkg/config"
 )
 
 // ConvertWorkflow creates new instances of the 'convert' workflow,
-// responsible for converting an old cluster config.
-func ConvertWorkflow(configFilePath string) Workflow {
+// responsible for converting an old cluster config. The converted config
+// is written at the given path, or printed if it is empty.
+func ConvertWorkflow(configFilePath, outputPath string) Workflow {
+	writeStep := printYAMLConfigStep
+	if outputPath != "" {
+		writeStep = writeYAMLConfigStep(outputPath)
+	}
 	return Workflow{
 		metadata: metadata{configFilePath: configFilePath},
 		steps: []Step{
 			readTFVarsConfigStep,
-			printYAMLConfigStep,
+			writeStep,
 		},
 	}
 }
 
+// readTFVarsConfigStep reads the tfvars config file of the metadata,
+// warning about the variables the conversion does not keep.
 func readTFVarsConfigStep(m *metadata) error {
+	return readTFVarsConfig(m, nil)
+}
+
+// readTFVarsConfig reads the tfvars config file of the metadata, warning
+// about the variables the conversion does not keep, except for the given
+// ones, which the workflow keeps by other means.
+func readTFVarsConfig(m *metadata, kept map[string]bool) error {
 	data, err := ioutil.ReadFile(m.configFilePath)
 	if err != nil {
 		return err
 	}
 
-	m.cluster = config.Cluster{}
-
-	return json.Unmarshal([]byte(data), &m.cluster)
+	cluster, unmapped, err := config.ParseTFVars(data)
+	if err != nil {
+		return fmt.Errorf("%s is not a valid tfvars file: %v", m.configFilePath, err)
+	}
+	var dropped []string
+	for _, name := range unmapped {
+		if !kept[name] {
+			dropped = append(dropped, name)
+		}
+	}
+	if len(dropped) > 0 {
+		log.Warnf("The following variables of %s have no equivalent in the config and are not converted: %s", m.configFilePath, strings.Join(dropped, ", "))
+	}
+	m.cluster = *cluster
+	return nil
 }
 
 func printYAMLConfigStep(m *metadata) error {
@@ -37,7 +66,26 @@ func printYAMLConfigStep(m *metadata) error {
 		return err
 	}
 
-	fmt.Println(yaml)
+	fmt.Fprintln(m.output(), yaml)
 
 	return nil
 }
+
+// writeYAMLConfigStep returns the step writing the converted config at the
+// given path, which must not exist.
+func writeYAMLConfigStep(path string) Step {
+	return func(m *metadata) error {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("config file already exists at %q", path)
+		}
+		yaml, err := m.cluster.YAML()
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(path, []byte(yaml), 0644); err != nil {
+			return fmt.Errorf("failed to write the config at %q: %v", path, err)
+		}
+		log.Infof("Wrote the converted config at %s", path)
+		return nil
+	}
+}
@@ -1,11 +1,27 @@
 package workflow
 
+import (
+	"fmt"
+	"text/tabwriter"
+)
+
+const destroyWorkflow = "destroy"
+
 // DestroyWorkflow creates new instances of the 'destroy' workflow,
 // responsible for running the actions required to remove resources
 // of an existing cluster and clean up any remaining artefacts.
-func DestroyWorkflow(clusterDir string) Workflow {
+// With continueOnError, the workflow attempts every step even if some fail,
+// retries the failed ones once, and reports the resources left behind.
+func DestroyWorkflow(clusterDir string, continueOnError bool) Workflow {
+	var report Step
+	if continueOnError {
+		report = printDestroyReportStep
+	}
 	return Workflow{
-		metadata: metadata{clusterDir: clusterDir},
+		name:            destroyWorkflow,
+		metadata:        metadata{clusterDir: clusterDir},
+		continueOnError: continueOnError,
+		report:          report,
 		steps: []Step{
 			refreshConfigStep,
 			destroyJoinMastersStep,
@@ -20,6 +36,31 @@ func DestroyWorkflow(clusterDir string) Workflow {
 	}
 }
 
+// DestroyDryRunWorkflow creates new instances of the 'destroy' workflow
+// which list the resources recorded in the state of every step instead of
+// destroying them. It does not change anything, so it neither locks nor
+// journals the cluster directory. It loads the config without refreshing the
+// tfvars generated from it.
+func DestroyDryRunWorkflow(clusterDir string) Workflow {
+	w := DestroyWorkflow(clusterDir, false)
+	w.readOnly = true
+	w.metadata.dryRun = make(map[string]bool)
+	w.steps = append([]Step{loadClusterConfigStep}, w.steps[1:]...)
+	return w
+}
+
+// destroyStepStates maps the destroy steps to the state they destroy.
+var destroyStepStates = map[string]string{
+	"destroyJoinMastersStep": mastersStep,
+	"destroyJoinWorkersStep": joinWorkersStep,
+	"destroyEtcdStep":        etcdStep,
+	"destroyBootstrapStep":   mastersStep,
+	"destroyTNCDNSStep":      tncDNSStep,
+	"destroyTopologyStep":    topologyStep,
+	"destroyAssetsStep":      assetsStep,
+	"destroyTLSAssetsStep":   tlsStep,
+}
+
 func destroyTLSAssetsStep(m *metadata) error {
 	return runDestroyStep(m, tlsStep)
 }
@@ -53,14 +94,96 @@ func destroyJoinMastersStep(m *metadata) error {
 }
 
 func runDestroyStep(m *metadata, step string, extraArgs ...string) error {
+	if m.dryRun != nil {
+		return listDestroyedResources(m, step)
+	}
 	if !hasStateFile(m.clusterDir, step) {
 		// there is no statefile, therefore nothing to destroy for this step
 		return nil
 	}
-	templateDir, err := findStepTemplates(step, m.cluster.Platform)
+	templateDir, err := m.findStepTemplates(step)
 	if err != nil {
 		return err
 	}
 
-	return tfDestroy(m.clusterDir, step, templateDir, extraArgs...)
+	return tfDestroy(m, step, templateDir, extraArgs...)
+}
+
+// listDestroyedResources prints the resources destroying the given step
+// would remove. Steps sharing their state with a step already listed are
+// not listed again.
+func listDestroyedResources(m *metadata, step string) error {
+	if m.dryRun[step] {
+		return nil
+	}
+	m.dryRun[step] = true
+
+	if !hasStateFile(m.clusterDir, step) {
+		fmt.Fprintf(m.output(), "%s: no state file, nothing to destroy\n\n", step)
+		return nil
+	}
+	state, err := readStateFile(m.clusterDir, step)
+	if err != nil {
+		return err
+	}
+	resources := state.resources()
+	fmt.Fprintf(m.output(), "%s: %d resources to destroy\n", step, len(resources))
+	if len(resources) > 0 {
+		w := tabwriter.NewWriter(m.output(), 0, 8, 2, ' ', 0)
+		fmt.Fprintln(w, "  TYPE\tNAME\tID")
+		for _, r := range resources {
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", r.Type, r.Address, r.ID)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintln(m.output())
+	return nil
+}
+
+// printDestroyReportStep prints, for every state destroyed by the workflow,
+// whether its steps failed and the resources still recorded in it.
+func printDestroyReportStep(m *metadata) error {
+	failed := make(map[string]bool)
+	for _, f := range m.failures {
+		failed[destroyStepStates[f.step]] = true
+	}
+
+	var leftovers []stateResource
+	w := tabwriter.NewWriter(m.output(), 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tSTATUS\tRESOURCES LEFT")
+	for _, step := range []string{mastersStep, joinWorkersStep, etcdStep, tncDNSStep, topologyStep, assetsStep, tlsStep} {
+		var resources []stateResource
+		if hasStateFile(m.clusterDir, step) {
+			state, err := readStateFile(m.clusterDir, step)
+			if err != nil {
+				return err
+			}
+			resources = state.resources()
+		}
+		status := "destroyed"
+		if failed[step] {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\n", step, status, len(resources))
+		for _, r := range resources {
+			r.Address = fmt.Sprintf("%s: %s", step, r.Address)
+			leftovers = append(leftovers, r)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(leftovers) == 0 {
+		return nil
+	}
+	fmt.Fprintln(m.output())
+	w = tabwriter.NewWriter(m.output(), 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "RESOURCE\tID")
+	for _, r := range leftovers {
+		fmt.Fprintf(w, "%s\t%s\n", r.Address, r.ID)
+	}
+	return w.Flush()
 }
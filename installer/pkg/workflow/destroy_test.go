@@ -0,0 +1,210 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDestroyWorkflowTerraformInvocations(t *testing.T) {
+	clusterDir, baseDir, cleanup := initTestClusterDir(t)
+	defer cleanup()
+
+	// Only the steps with a state file are destroyed.
+	for _, step := range []string{tlsStep, topologyStep, mastersStep, joinWorkersStep} {
+		if err := writeFile(stateFilePath(clusterDir, step), "{}"); err != nil {
+			t.Fatalf("failed to write %s state file: %v", step, err)
+		}
+	}
+
+	ex := &fakeExecutor{}
+	w := DestroyWorkflow(clusterDir, false)
+	w.metadata.baseDir = baseDir
+	w.metadata.executor = ex
+	if err := w.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to execute workflow: %v", err)
+	}
+
+	expected := []string{
+		"destroy masters tectonic_bootstrap=false",
+		"destroy joining_workers",
+		"destroy masters tectonic_bootstrap=false",
+		"destroy topology",
+		"destroy tls",
+	}
+	if got := ex.commands("destroy"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected destroys %v, got %v", expected, got)
+	}
+}
+
+func TestDestroyWorkflowContinueOnError(t *testing.T) {
+	testCases := []struct {
+		test string
+		// failures is the number of times destroying each state fails.
+		failures map[string]int
+		expected []string
+		err      string
+	}{
+		{
+			test:     "retried steps succeed",
+			failures: map[string]int{mastersStep: 2},
+			expected: []string{
+				"destroy masters tectonic_bootstrap=false",
+				"destroy joining_workers",
+				"destroy masters tectonic_bootstrap=false",
+				"destroy topology",
+				"destroy tls",
+				"destroy masters tectonic_bootstrap=false",
+				"destroy masters tectonic_bootstrap=false",
+			},
+		},
+		{
+			test:     "retried step fails",
+			failures: map[string]int{topologyStep: 2},
+			expected: []string{
+				"destroy masters tectonic_bootstrap=false",
+				"destroy joining_workers",
+				"destroy masters tectonic_bootstrap=false",
+				"destroy topology",
+				"destroy tls",
+				"destroy topology",
+			},
+			err: "step destroyTopologyStep failed",
+		},
+		{
+			test:     "retried steps fail",
+			failures: map[string]int{topologyStep: 2, tlsStep: 2},
+			expected: []string{
+				"destroy masters tectonic_bootstrap=false",
+				"destroy joining_workers",
+				"destroy masters tectonic_bootstrap=false",
+				"destroy topology",
+				"destroy tls",
+				"destroy topology",
+				"destroy tls",
+			},
+			err: "2 steps failed: destroyTopologyStep, destroyTLSAssetsStep",
+		},
+	}
+
+	for _, tc := range testCases {
+		clusterDir, baseDir, cleanup := initTestClusterDir(t)
+		defer cleanup()
+		for _, step := range []string{tlsStep, topologyStep, mastersStep, joinWorkersStep} {
+			writeTestState(t, clusterDir, step, nil)
+		}
+
+		ex := &fakeExecutor{
+			fail: func(i invocation) error {
+				if i.command() == "destroy" && tc.failures[i.state()] > 0 {
+					tc.failures[i.state()]--
+					return errors.New("exit status 1")
+				}
+				return nil
+			},
+		}
+		w := DestroyWorkflow(clusterDir, true)
+		w.metadata.baseDir = baseDir
+		w.metadata.executor = ex
+		err := w.Execute(context.Background())
+		if tc.err == "" && err != nil {
+			t.Errorf("test case %s: failed to execute workflow: %v", tc.test, err)
+		}
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("test case %s: expected error %q, got %v", tc.test, tc.err, err)
+		}
+
+		if got := ex.commands("destroy"); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("test case %s: expected destroys %v, got %v", tc.test, tc.expected, got)
+		}
+	}
+}
+
+func TestDestroyDryRunWorkflow(t *testing.T) {
+	clusterDir, baseDir, cleanup := initTestClusterDir(t)
+	defer cleanup()
+	writeTestState(t, clusterDir, mastersStep, map[string]tfStateResource{
+		"aws_autoscaling_group.masters": testResource("aws_autoscaling_group", "masters", map[string]string{"desired_capacity": "3"}),
+	})
+
+	before := snapshotDir(t, clusterDir)
+
+	var out bytes.Buffer
+	ex := &fakeExecutor{}
+	w := DestroyDryRunWorkflow(clusterDir)
+	w.metadata.baseDir = baseDir
+	w.metadata.executor = ex
+	w.metadata.out = &out
+	if err := w.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to execute workflow: %v", err)
+	}
+
+	expected := `masters: 1 resources to destroy
+  TYPE                   NAME                           ID
+  aws_autoscaling_group  aws_autoscaling_group.masters  masters
+
+joining_workers: no state file, nothing to destroy
+
+etcd: no state file, nothing to destroy
+
+tnc_dns: no state file, nothing to destroy
+
+topology: no state file, nothing to destroy
+
+assets: no state file, nothing to destroy
+
+tls: no state file, nothing to destroy
+
+`
+	if out.String() != expected {
+		t.Errorf("expected the listing:\n%s\ngot:\n%s", expected, out.String())
+	}
+	if after := snapshotDir(t, clusterDir); !reflect.DeepEqual(after, before) {
+		t.Errorf("expected the cluster directory to be unchanged, got %v instead of %v", keys(after), keys(before))
+	}
+
+	if len(ex.invocations) != 0 {
+		t.Errorf("expected no terraform invocations, got %v", ex.invocations)
+	}
+	if _, err := readJournal(clusterDir); err == nil {
+		t.Error("expected the dry run not to be journaled")
+	}
+}
+
+// snapshotDir returns the contents of the files under the given directory,
+// by path.
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[path] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", dir, err)
+	}
+	return files
+}
+
+// keys returns the sorted keys of the given map.
+func keys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,115 @@
+package workflow
+
+import (
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// EventSchemaVersion is the version of the schema of the events emitted by
+// workflows. It is increased on any incompatible change to Event.
+const EventSchemaVersion = 1
+
+// EventType is the type of an event emitted by a workflow.
+type EventType string
+
+const (
+	// EventWorkflowStarted is emitted before the first step of a workflow runs.
+	EventWorkflowStarted EventType = "workflow-started"
+	// EventWorkflowFinished is emitted once a workflow succeeded or failed.
+	EventWorkflowFinished EventType = "workflow-finished"
+	// EventStepStarted is emitted before a step runs.
+	EventStepStarted EventType = "step-started"
+	// EventStepFinished is emitted once a step succeeded or failed.
+	EventStepFinished EventType = "step-finished"
+	// EventTerraformResources is emitted once TerraForm applied or destroyed
+	// the resources of a step.
+	EventTerraformResources EventType = "terraform-resources"
+	// EventValidationFailed is emitted when the cluster config is invalid.
+	EventValidationFailed EventType = "validation-failed"
+)
+
+var terraformResourcesRegexp = regexp.MustCompile(`Resources: (?:(\d+) added, (\d+) changed, )?(\d+) destroyed`)
+
+// Event describes the progress of a workflow. Events are emitted as
+// newline-delimited JSON objects.
+type Event struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Time          time.Time `json:"time"`
+	Type          EventType `json:"type"`
+	Workflow      string    `json:"workflow,omitempty"`
+	// Step is the name of the step for step and terraform events.
+	Step string `json:"step,omitempty"`
+	// Steps lists the steps of the workflow for workflow-started events.
+	Steps []string `json:"steps,omitempty"`
+	// Status is the status of the step for step-finished events, and the
+	// result of the workflow for workflow-finished events.
+	Status string `json:"status,omitempty"`
+	// DurationSeconds is the duration of the step or workflow for
+	// step-finished and workflow-finished events.
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	// Resources holds the resource counts reported by TerraForm for
+	// terraform-resources events.
+	Resources *ResourceCounts `json:"resources,omitempty"`
+	// Errors lists the errors of validation-failed events.
+	Errors []string `json:"errors,omitempty"`
+	// Error is the error a step or workflow failed with.
+	Error string `json:"error,omitempty"`
+}
+
+// ResourceCounts are the numbers of resources changed by a TerraForm run.
+type ResourceCounts struct {
+	Added     int `json:"added"`
+	Changed   int `json:"changed"`
+	Destroyed int `json:"destroyed"`
+}
+
+// eventEmitter writes the events of a workflow to an output.
+type eventEmitter struct {
+	encoder  *json.Encoder
+	workflow string
+}
+
+// WithEvents returns a copy of the workflow emitting its progress as
+// newline-delimited JSON events to out. The output of TerraForm and the
+// reports of the steps are then written to stderr rather than stdout, so
+// that out can be stdout.
+func (w Workflow) WithEvents(out io.Writer) Workflow {
+	w.metadata.events = &eventEmitter{
+		encoder:  json.NewEncoder(out),
+		workflow: w.name,
+	}
+	return w
+}
+
+// emit writes an event if the workflow emits events.
+func (m *metadata) emit(e Event) {
+	if m.events == nil {
+		return
+	}
+	e.SchemaVersion = EventSchemaVersion
+	e.Time = time.Now().UTC()
+	e.Workflow = m.events.workflow
+	if err := m.events.encoder.Encode(e); err != nil {
+		log.Warnf("failed to emit %s event: %v", e.Type, err)
+	}
+}
+
+// emitTerraformResources emits the resource counts TerraForm reported in the
+// output of an apply or destroy of the given step.
+func (m *metadata) emitTerraformResources(step, output string) {
+	match := terraformResourcesRegexp.FindStringSubmatch(ansiEscapeRegexp.ReplaceAllString(output, ""))
+	if match == nil {
+		return
+	}
+	counts := &ResourceCounts{}
+	// Added and changed are not reported by destroys.
+	counts.Added, _ = strconv.Atoi(match[1])
+	counts.Changed, _ = strconv.Atoi(match[2])
+	counts.Destroyed, _ = strconv.Atoi(match[3])
+	m.emit(Event{Type: EventTerraformResources, Step: step, Resources: counts})
+}
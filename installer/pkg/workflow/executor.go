@@ -1,14 +1,24 @@
 package workflow
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sync"
 )
 
+// terraformExecutor runs TerraForm commands on behalf of the workflow steps.
+// It is implemented by executor, and can be replaced to run workflows without
+// calling TerraForm.
+type terraformExecutor interface {
+	execute(ctx context.Context, clusterDir string, env []string, out io.Writer, args ...string) error
+}
+
 // executor enables calling TerraForm from Go, across platforms, with any
 // additional providers/provisioners that the currently executing binary
 // exposes.
@@ -17,6 +27,8 @@ import (
 // the current working directory or in the PATH.
 type executor struct {
 	binaryPath string
+	// stdout is where the output of TerraForm is streamed to.
+	stdout io.Writer
 }
 
 // Set the binary names for different platforms
@@ -31,9 +43,35 @@ var errBinaryNotFound = errors.New(
 	"TerraForm not in executable's folder, cwd nor PATH",
 )
 
+// killKey is the key of the context value holding the channel closed once
+// the TerraForm commands running under the context must be killed.
+type killKey struct{}
+
+// WithKill returns a copy of the parent context, cancelled by the returned
+// function along with the parent, which also kills the TerraForm command
+// running under the context, and the processes it started, instead of
+// waiting for it to stop gracefully. The workflow then stops as if it was
+// interrupted.
+func WithKill(parent context.Context) (context.Context, context.CancelFunc) {
+	killed := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.WithValue(parent, killKey{}, killed))
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() { close(killed) })
+		cancel()
+	}
+}
+
+// killed returns the channel closed once the TerraForm commands running
+// under the given context must be killed, or nil if they never are.
+func killed(ctx context.Context) <-chan struct{} {
+	c, _ := ctx.Value(killKey{}).(chan struct{})
+	return c
+}
+
 // newExecutor initializes a new Executor.
 func newExecutor() (*executor, error) {
-	ex := new(executor)
+	ex := &executor{stdout: os.Stdout}
 
 	// Find the TerraForm binary.
 	binPath, err := tfBinaryPath()
@@ -45,12 +83,19 @@ func newExecutor() (*executor, error) {
 	return ex, nil
 }
 
-// Execute runs the given command and arguments against TerraForm.
+// Execute runs the given command and arguments against TerraForm, with the
+// given environment variables added to the environment of the installer.
+// The output of TerraForm is streamed to the console and, if out is not nil,
+// copied to out as well.
+//
+// When the context is cancelled, TerraForm is interrupted and given the
+// chance to release its locks and persist its state before returning, unless
+// it is killed through WithKill.
 //
 // An error is returned if the TerraForm binary could not be found, or if the
 // TerraForm call itself failed, in which case, details can be found in the
 // output.
-func (ex *executor) execute(clusterDir string, args ...string) error {
+func (ex *executor) execute(ctx context.Context, clusterDir string, env []string, out io.Writer, args ...string) error {
 	// Prepare TerraForm command by setting up the command, configuration,
 	// and the working directory
 	if clusterDir == "" {
@@ -58,13 +103,52 @@ func (ex *executor) execute(clusterDir string, args ...string) error {
 	}
 
 	cmd := exec.Command(ex.binaryPath, args...)
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
+	cmd.Stdout = ex.stdout
 	cmd.Stderr = os.Stderr
+	if out != nil {
+		cmd.Stdout = io.MultiWriter(ex.stdout, out)
+		cmd.Stderr = io.MultiWriter(os.Stderr, out)
+	}
 	cmd.Dir = clusterDir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	prepareCommand(cmd)
 
 	// Start TerraForm.
-	return cmd.Run()
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+	}
+	select {
+	case <-killed(ctx):
+	default:
+		if err := interruptCommand(cmd); err != nil {
+			killCommand(cmd)
+			<-done
+			return fmt.Errorf("failed to interrupt TerraForm: %v", err)
+		}
+	}
+
+	// Wait for TerraForm to shut down gracefully, unless it is killed.
+	select {
+	case <-done:
+	case <-killed(ctx):
+		if err := killCommand(cmd); err != nil {
+			return fmt.Errorf("failed to kill TerraForm: %v", err)
+		}
+		<-done
+	}
+	return ctx.Err()
 }
 
 // tfBinatyPath searches for a TerraForm binary on disk:
@@ -0,0 +1,30 @@
+//go:build !windows
+// +build !windows
+
+package workflow
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// prepareCommand runs TerraForm in its own process group, so that signals
+// sent to the installer from the terminal are not delivered to TerraForm
+// directly. They are forwarded once by interruptCommand instead, as
+// TerraForm aborts without persisting its state on a second interrupt.
+// Its stdin is left unset, as reading from the terminal from a background
+// process group would stop it.
+func prepareCommand(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// interruptCommand asks TerraForm to stop gracefully.
+func interruptCommand(cmd *exec.Cmd) error {
+	return cmd.Process.Signal(syscall.SIGINT)
+}
+
+// killCommand kills TerraForm and the processes it started, like its
+// providers, which share its process group.
+func killCommand(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,130 @@
+//go:build !windows
+// +build !windows
+
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// runTerraformStep runs the TerraForm executor of the metadata.
+func runTerraformStep(m *metadata) error {
+	return m.executor.execute(m.ctx, m.clusterDir, nil, nil, "apply")
+}
+
+// waitForFile waits for the file at the given path to exist.
+func waitForFile(path string) error {
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("timed out waiting for %s", path)
+}
+
+func TestExecutorInterrupt(t *testing.T) {
+	testCases := []struct {
+		test string
+		// script is the fake TerraForm, which creates the ready file once
+		// it handles signals.
+		script string
+		kill   bool
+	}{
+		{
+			test: "interrupted",
+			script: `trap 'echo interrupted > interrupted; exit 1' INT
+touch ready
+while true; do sleep 0.1; done
+`,
+		},
+		{
+			test: "killed",
+			script: `trap '' INT
+(while true; do echo tick >> ticks; sleep 0.1; done) &
+touch ready
+while true; do sleep 0.1; done
+`,
+			kill: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		clusterDir, err := ioutil.TempDir("", "executor")
+		if err != nil {
+			t.Fatalf("failed to create cluster dir: %v", err)
+		}
+		defer os.RemoveAll(clusterDir)
+		binaryPath := filepath.Join(clusterDir, "terraform")
+		if err := ioutil.WriteFile(binaryPath, []byte("#!/bin/sh\n"+tc.script), 0755); err != nil {
+			t.Fatalf("failed to write the fake terraform: %v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ctx, kill := WithKill(ctx)
+		defer kill()
+		go func() {
+			if err := waitForFile(filepath.Join(clusterDir, "ready")); err != nil {
+				t.Error(err)
+			}
+			cancel()
+			if tc.kill {
+				// The fake TerraForm ignores the interrupt.
+				time.Sleep(200 * time.Millisecond)
+				kill()
+			}
+		}()
+
+		w := Workflow{
+			name: "test",
+			metadata: metadata{
+				clusterDir: clusterDir,
+				executor:   &executor{binaryPath: binaryPath, stdout: ioutil.Discard},
+			},
+			steps: []Step{runTerraformStep},
+		}
+		done := make(chan error, 1)
+		go func() { done <- w.Execute(ctx) }()
+		select {
+		case err = <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("test case %s: timed out waiting for the workflow to stop", tc.test)
+		}
+		if err == nil {
+			t.Errorf("test case %s: expected the interrupted workflow to fail", tc.test)
+		}
+
+		j, err := readJournal(clusterDir)
+		if err != nil {
+			t.Fatalf("test case %s: failed to read journal: %v", tc.test, err)
+		}
+		if j.Steps[0].Status != stepInterrupted {
+			t.Errorf("test case %s: expected the step to be %s, got %s", tc.test, stepInterrupted, j.Steps[0].Status)
+		}
+		if _, err := os.Stat(filepath.Join(clusterDir, lockFileName)); !os.IsNotExist(err) {
+			t.Errorf("test case %s: expected the lock to be released, got %v", tc.test, err)
+		}
+
+		if !tc.kill {
+			if _, err := os.Stat(filepath.Join(clusterDir, "interrupted")); err != nil {
+				t.Errorf("test case %s: expected terraform to be interrupted: %v", tc.test, err)
+			}
+			continue
+		}
+		// The processes started by TerraForm are killed along with it.
+		ticks, err := ioutil.ReadFile(filepath.Join(clusterDir, "ticks"))
+		if err != nil {
+			t.Fatalf("test case %s: failed to read the ticks: %v", tc.test, err)
+		}
+		time.Sleep(300 * time.Millisecond)
+		if later, _ := ioutil.ReadFile(filepath.Join(clusterDir, "ticks")); len(later) != len(ticks) {
+			t.Errorf("test case %s: expected the processes of terraform to be killed", tc.test)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package workflow
+
+import (
+	"os"
+	"os/exec"
+)
+
+// prepareCommand lets TerraForm share the console of the installer.
+func prepareCommand(cmd *exec.Cmd) {
+	cmd.Stdin = os.Stdin
+}
+
+// interruptCommand is a no-op, as the console already delivers Ctrl-C to
+// TerraForm, and a second interrupt would make it abort without persisting
+// its state.
+func interruptCommand(cmd *exec.Cmd) error {
+	return nil
+}
+
+// killCommand kills TerraForm.
+func killCommand(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
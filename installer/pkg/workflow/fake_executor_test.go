@@ -0,0 +1,101 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// fakeExecutor is a terraformExecutor recording every invocation instead of
+// running TerraForm. Successfully applying a step creates its state file.
+type fakeExecutor struct {
+	invocations []invocation
+	// output returns the output the given invocation prints, if any.
+	output func(invocation) string
+	// fail returns the error the given invocation fails with, if any.
+	fail func(invocation) error
+}
+
+// invocation is a recorded call to TerraForm.
+type invocation struct {
+	dir  string
+	args []string
+	vars map[string]string
+	env  []string
+}
+
+// command returns the TerraForm command of the invocation, e.g. "apply".
+func (i invocation) command() string {
+	return i.args[0]
+}
+
+// state returns the name of the state the invocation operates on, if any.
+func (i invocation) state() string {
+	for _, arg := range i.args {
+		if strings.HasPrefix(arg, "-state=") {
+			return strings.TrimSuffix(strings.TrimPrefix(arg, "-state="), ".tfstate")
+		}
+	}
+	return ""
+}
+
+// String returns the command, state and sorted variables of the invocation,
+// e.g. "apply masters tectonic_bootstrap=true".
+func (i invocation) String() string {
+	parts := []string{i.command()}
+	if state := i.state(); state != "" {
+		parts = append(parts, state)
+	}
+	var vars []string
+	for k, v := range i.vars {
+		vars = append(vars, fmt.Sprintf("%s=%s", k, v))
+	}
+	sort.Strings(vars)
+	return strings.Join(append(parts, vars...), " ")
+}
+
+func (f *fakeExecutor) execute(ctx context.Context, clusterDir string, env []string, out io.Writer, args ...string) error {
+	i := invocation{
+		dir:  clusterDir,
+		args: args,
+		vars: make(map[string]string),
+		env:  env,
+	}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-var=") {
+			kv := strings.SplitN(strings.TrimPrefix(arg, "-var="), "=", 2)
+			i.vars[kv[0]] = kv[1]
+		}
+	}
+	f.invocations = append(f.invocations, i)
+
+	if f.output != nil && out != nil {
+		if _, err := io.WriteString(out, f.output(i)); err != nil {
+			return err
+		}
+	}
+
+	if f.fail != nil {
+		if err := f.fail(i); err != nil {
+			return err
+		}
+	}
+	if i.command() == "apply" {
+		return writeFile(stateFilePath(clusterDir, i.state()), "{}")
+	}
+	return nil
+}
+
+// commands returns the string representation of the recorded invocations
+// of the given TerraForm command.
+func (f *fakeExecutor) commands(command string) []string {
+	var commands []string
+	for _, i := range f.invocations {
+		if i.command() == command {
+			commands = append(commands, i.String())
+		}
+	}
+	return commands
+}
@@ -3,13 +3,17 @@ package workflow
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
+	log "github.com/Sirupsen/logrus"
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/coreos/tectonic-installer/installer/pkg/config"
 	configgenerator "github.com/coreos/tectonic-installer/installer/pkg/config-generator"
+	"github.com/coreos/tectonic-installer/installer/pkg/wizard"
 )
 
 const (
@@ -18,6 +22,7 @@ const (
 	tncoConfigFileName         = "tnco-config.yaml"
 	kubeSystemPath             = "generated/manifests"
 	kubeSystemFileName         = "cluster-config.yaml"
+	logsPath                   = "logs"
 	tectonicSystemPath         = "generated/tectonic"
 	newTLSPath                 = "generated/newTLS"
 	tectonicSystemFileName     = "cluster-config.yaml"
@@ -25,17 +30,51 @@ const (
 )
 
 // InitWorkflow creates new instances of the 'init' workflow,
-// responsible for initializing a new cluster.
-func InitWorkflow(configFilePath string) Workflow {
+// responsible for initializing a new cluster. The given overlays are merged
+// over the config file, in order, into the config of the cluster.
+func InitWorkflow(configFilePath string, overlays ...string) Workflow {
+	return Workflow{
+		metadata: metadata{configFilePath: configFilePath, configOverlays: overlays},
+		steps: []Step{
+			prepareWorspaceStep,
+			refreshConfigStep,
+		},
+	}
+}
+
+// InitWizardWorkflow creates new instances of the 'init --interactive'
+// workflow, responsible for writing the config of a new cluster at the
+// given path from the answers to the wizard, and initializing the cluster.
+func InitWizardWorkflow(configFilePath string, wiz *wizard.Wizard) Workflow {
 	return Workflow{
 		metadata: metadata{configFilePath: configFilePath},
 		steps: []Step{
+			writeWizardConfigStep(wiz),
 			prepareWorspaceStep,
 			refreshConfigStep,
 		},
 	}
 }
 
+// writeWizardConfigStep returns the step writing the config built by the
+// given wizard at the config file path of the metadata.
+func writeWizardConfigStep(wiz *wizard.Wizard) Step {
+	return func(m *metadata) error {
+		if _, err := os.Stat(m.configFilePath); err == nil {
+			return fmt.Errorf("config file already exists at %q", m.configFilePath)
+		}
+		data, err := wiz.Run()
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(m.configFilePath, data, 0644); err != nil {
+			return fmt.Errorf("failed to write the config at %q: %v", m.configFilePath, err)
+		}
+		log.Infof("Wrote the config of the cluster at %s", m.configFilePath)
+		return nil
+	}
+}
+
 func buildInternalConfig(clusterDir string) error {
 	if clusterDir == "" {
 		return errors.New("no cluster dir given for building internal config")
@@ -46,10 +85,12 @@ func buildInternalConfig(clusterDir string) error {
 	if err != nil {
 		return err
 	}
-	internalCfg := config.Internal{
-		ClusterID: clusterID,
-	}
+	return writeInternalConfig(clusterDir, config.Internal{ClusterID: clusterID})
+}
 
+// writeInternalConfig writes the given internal config in the cluster
+// directory.
+func writeInternalConfig(clusterDir string, internalCfg config.Internal) error {
 	// store the content
 	yamlContent, err := yaml.Marshal(internalCfg)
 	internalFileContent := []byte("# Do not touch, auto-generated\n")
@@ -60,8 +101,10 @@ func buildInternalConfig(clusterDir string) error {
 	return writeFile(filepath.Join(clusterDir, internalFileName), string(internalFileContent))
 }
 
+// generateTerraformVariablesStep writes the tfvars of the cluster config of
+// the metadata. The libvirt worker domains already applied keep their index.
 func generateTerraformVariablesStep(m *metadata) error {
-	vars, err := m.cluster.TFVars()
+	vars, err := terraformVariables(m)
 	if err != nil {
 		return err
 	}
@@ -70,6 +113,20 @@ func generateTerraformVariablesStep(m *metadata) error {
 	return writeFile(terraformVariablesFilePath, vars)
 }
 
+// terraformVariables returns the variables of the cluster config of the
+// metadata in tfvars format. On libvirt, the worker domains already applied
+// keep their index, as recorded in the state of the cluster directory.
+func terraformVariables(m *metadata) (string, error) {
+	if m.cluster.Platform == config.PlatformLibvirt && hasStateFile(m.clusterDir, joinWorkersStep) {
+		state, err := readStateFile(m.clusterDir, joinWorkersStep)
+		if err != nil {
+			return "", err
+		}
+		m.cluster.Libvirt.AppliedWorkerNodes = state.libvirtDomainNames()
+	}
+	return m.cluster.TFVars()
+}
+
 func prepareWorspaceStep(m *metadata) error {
 	dir, err := os.Getwd()
 	if err != nil {
@@ -81,17 +138,39 @@ func prepareWorspaceStep(m *metadata) error {
 	}
 
 	// load initial cluster config to get cluster.Name
-	cluster, err := readClusterConfig(m.configFilePath, "")
+	paths := append([]string{m.configFilePath}, m.configOverlays...)
+	merged, err := config.MergeConfigFiles(paths...)
+	if err != nil {
+		return fmt.Errorf("failed to get configuration from files %q: %v", paths, err)
+	}
+	resolved, err := config.InterpolateConfig(merged, dir)
+	if err != nil {
+		return fmt.Errorf("failed to get configuration from files %q: %v", paths, err)
+	}
+	cluster, err := config.ParseConfig(resolved)
 	if err != nil {
-		return fmt.Errorf("failed to get configuration from file %q: %v", m.configFilePath, err)
+		return fmt.Errorf("failed to get configuration from files %q: %v", paths, err)
+	}
+
+	clusterDir := filepath.Join(dir, cluster.Name)
+
+	// Without an admin password, the config references a generated one.
+	var password string
+	if cluster.Admin.Password == "" && cluster.Admin.PasswordFrom == nil {
+		if password, err = config.GeneratePassword(); err != nil {
+			return err
+		}
+		cluster.Admin.PasswordFrom = &config.SecretRef{File: filepath.Join(clusterDir, adminPasswordFileName)}
+		if merged, err = withAdminPasswordFile(merged, cluster.Admin.PasswordFrom.File); err != nil {
+			return err
+		}
 	}
 
-	if err := cluster.ValidateAndLog(); err != nil {
+	if err := validateClusterConfig(m, cluster); err != nil {
 		return err
 	}
 
 	// generate clusterDir folder
-	clusterDir := filepath.Join(dir, cluster.Name)
 	m.clusterDir = clusterDir
 	if stat, err := os.Stat(clusterDir); err == nil && stat.IsDir() {
 		return fmt.Errorf("cluster directory already exists at %q", clusterDir)
@@ -101,12 +180,56 @@ func prepareWorspaceStep(m *metadata) error {
 		return fmt.Errorf("failed to create cluster directory at %q", clusterDir)
 	}
 
+	if password != "" {
+		if err := writeAdminPassword(filepath.Join(clusterDir, adminPasswordFileName), password); err != nil {
+			return err
+		}
+	}
+
 	// put config file under the clusterDir folder
 	configFilePath := filepath.Join(clusterDir, configFileName)
-	if err := copyFile(m.configFilePath, configFilePath); err != nil {
+	if err := writeEffectiveConfig(m, configFilePath, merged, password != ""); err != nil {
 		return fmt.Errorf("failed to create cluster config at %q: %v", clusterDir, err)
 	}
 
 	// generate the internal config file under the clusterDir folder
 	return buildInternalConfig(clusterDir)
 }
+
+// withAdminPasswordFile returns the given config with its admin password
+// referenced from the file at the given path. The other values of the
+// config, and their references, are kept as is.
+func withAdminPasswordFile(data []byte, path string) ([]byte, error) {
+	var cfg yaml.MapSlice
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	passwordFrom := yaml.MapItem{Key: "passwordFrom", Value: yaml.MapSlice{{Key: "file", Value: path}}}
+	for i, item := range cfg {
+		if item.Key != "admin" {
+			continue
+		}
+		admin, _ := item.Value.(yaml.MapSlice)
+		cfg[i].Value = append(admin, passwordFrom)
+		return yaml.Marshal(cfg)
+	}
+	cfg = append(cfg, yaml.MapItem{Key: "admin", Value: yaml.MapSlice{passwordFrom}})
+	return yaml.Marshal(cfg)
+}
+
+// writeEffectiveConfig writes the merged config of the init workflow at the
+// given path, its references being resolved when the config is loaded. A
+// single config without references nor relative secret files, which init did
+// not change, is copied as is instead, keeping its comments.
+func writeEffectiveConfig(m *metadata, path string, merged []byte, changed bool) error {
+	data, err := ioutil.ReadFile(m.configFilePath)
+	if err != nil {
+		return err
+	}
+	if len(m.configOverlays) == 0 && !config.HasReferences(data) && !config.HasRelativeSecretFiles(data) && !changed {
+		return copyFile(m.configFilePath, path)
+	}
+
+	header := fmt.Sprintf("# The effective config of the cluster, merged from %s by 'tectonic init'.\n", strings.Join(append([]string{m.configFilePath}, m.configOverlays...), ", "))
+	return writeFile(path, header+string(merged))
+}
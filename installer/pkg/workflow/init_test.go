@@ -9,7 +9,9 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -190,3 +192,114 @@ func TestBuildInternalConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestPrepareWorkspaceStepKeepsReferences(t *testing.T) {
+	dir, err := ioutil.TempDir("", "init")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ps, lic, err := generatePullSecretAndLicense("init_workflow", time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("failed to generate pull secret and license: %v", err)
+	}
+	defer os.Remove(ps.Name())
+	defer os.Remove(lic.Name())
+
+	const password = "interpolated-admin-password"
+	os.Setenv("TEST_INIT_ADMIN_PASSWORD", password)
+	defer os.Unsetenv("TEST_INIT_ADMIN_PASSWORD")
+	overlay := filepath.Join(dir, "secrets.yaml")
+	content := fmt.Sprintf("admin:\n  password: ${TEST_INIT_ADMIN_PASSWORD}\npullSecretPath: %s\nlicensePath: %s\n", ps.Name(), lic.Name())
+	if err := ioutil.WriteFile(overlay, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write the overlay: %v", err)
+	}
+	base, err := filepath.Abs("./fixtures/aws.basic.yaml")
+	if err != nil {
+		t.Fatalf("failed to get the fixture path: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	m := &metadata{configFilePath: base, configOverlays: []string{overlay}}
+	if err := prepareWorspaceStep(m); err != nil {
+		t.Fatalf("failed to prepare the workspace: %v", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(m.clusterDir, configFileName))
+	if err != nil {
+		t.Fatalf("failed to read the cluster config: %v", err)
+	}
+	if strings.Contains(string(data), password) {
+		t.Errorf("expected the cluster config not to hold the interpolated password, got:\n%s", data)
+	}
+
+	if err := loadClusterConfigStep(m); err != nil {
+		t.Fatalf("failed to load the cluster config: %v", err)
+	}
+	if m.cluster.Admin.Password != password {
+		t.Errorf("expected the admin password to be resolved to %q, got %q", password, m.cluster.Admin.Password)
+	}
+}
+
+func TestGenerateTerraformVariablesStepLibvirtWorkers(t *testing.T) {
+	clusterDir, err := ioutil.TempDir("", "init")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(clusterDir)
+
+	// The gpu pool scales up among the applied pools.
+	cluster, err := config.ParseConfig([]byte(`platform: libvirt
+libvirt: {network: {ipRange: 192.168.124.0/24}}
+etcd:
+  nodePools: [etcd]
+master:
+  nodePools: [master]
+worker:
+  nodePools: [worker, gpu, big]
+nodePools:
+  - {name: etcd, count: 1}
+  - {name: master, count: 1}
+  - {name: worker, count: 2}
+  - {name: gpu, count: 2}
+  - {name: big, count: 1}
+`))
+	if err != nil {
+		t.Fatalf("failed to parse the config: %v", err)
+	}
+	domains := make(map[string]tfStateResource)
+	for i, name := range []string{"worker0", "worker1", "worker-gpu-0", "worker-big-0"} {
+		domains[fmt.Sprintf("libvirt_domain.worker.%d", i)] = testResource("libvirt_domain", name, map[string]string{"name": name})
+	}
+	writeTestState(t, clusterDir, joinWorkersStep, domains)
+
+	m := &metadata{clusterDir: clusterDir, cluster: *cluster}
+	if err := generateTerraformVariablesStep(m); err != nil {
+		t.Fatalf("failed to generate the tfvars: %v", err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(clusterDir, terraformVariablesFileName))
+	if err != nil {
+		t.Fatalf("failed to read the tfvars: %v", err)
+	}
+	var vars struct {
+		Nodes     []string `json:"tectonic_libvirt_worker_nodes"`
+		PoolNodes []string `json:"tectonic_libvirt_worker_pool_nodes"`
+	}
+	if err := json.Unmarshal(data, &vars); err != nil {
+		t.Fatalf("failed to unmarshal the tfvars: %v", err)
+	}
+	// The applied domains keep their index, the new one follows.
+	if expected := []string{"worker0", "worker1", "worker-gpu-0", "worker-big-0", "worker-gpu-1"}; !reflect.DeepEqual(vars.Nodes, expected) {
+		t.Errorf("expected the worker nodes %v, got %v", expected, vars.Nodes)
+	}
+	if expected := []string{"0", "0", "1", "2", "1"}; !reflect.DeepEqual(vars.PoolNodes, expected) {
+		t.Errorf("expected the pools of the worker nodes %v, got %v", expected, vars.PoolNodes)
+	}
+}
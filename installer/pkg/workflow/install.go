@@ -1,6 +1,7 @@
 package workflow
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,10 +9,31 @@ import (
 	"github.com/coreos/tectonic-installer/installer/pkg/config-generator"
 )
 
+const (
+	installFullWorkflow      = "install-full"
+	installTLSNewWorkflow    = "install-newtls"
+	installTLSWorkflow       = "install-tls"
+	installAssetsWorkflow    = "install-assets"
+	installBootstrapWorkflow = "install-bootstrap"
+	installJoinWorkflow      = "install-join"
+)
+
+// resumableWorkflows maps the name of the workflows that can be resumed
+// to the function creating them.
+var resumableWorkflows = map[string]func(string) Workflow{
+	installFullWorkflow:      InstallFullWorkflow,
+	installTLSNewWorkflow:    InstallTLSNewWorkflow,
+	installTLSWorkflow:       InstallTLSWorkflow,
+	installAssetsWorkflow:    InstallAssetsWorkflow,
+	installBootstrapWorkflow: InstallBootstrapWorkflow,
+	installJoinWorkflow:      InstallJoinWorkflow,
+}
+
 // InstallFullWorkflow creates new instances of the 'install' workflow,
 // responsible for running the actions necessary to install a new cluster.
 func InstallFullWorkflow(clusterDir string) Workflow {
 	return Workflow{
+		name:     installFullWorkflow,
 		metadata: metadata{clusterDir: clusterDir},
 		steps: []Step{
 			refreshConfigStep,
@@ -35,6 +57,7 @@ func InstallFullWorkflow(clusterDir string) Workflow {
 // InstallTLSNewWorkflow generates the TLS certificates using go, instead of TF
 func InstallTLSNewWorkflow(clusterDir string) Workflow {
 	return Workflow{
+		name:     installTLSNewWorkflow,
 		metadata: metadata{clusterDir: clusterDir},
 		steps: []Step{
 			refreshConfigStep,
@@ -48,6 +71,7 @@ func InstallTLSNewWorkflow(clusterDir string) Workflow {
 // "assets" step
 func InstallTLSWorkflow(clusterDir string) Workflow {
 	return Workflow{
+		name:     installTLSWorkflow,
 		metadata: metadata{clusterDir: clusterDir},
 		steps: []Step{
 			refreshConfigStep,
@@ -60,6 +84,7 @@ func InstallTLSWorkflow(clusterDir string) Workflow {
 // responsible for running the actions necessary to generate cluster assets.
 func InstallAssetsWorkflow(clusterDir string) Workflow {
 	return Workflow{
+		name:     installAssetsWorkflow,
 		metadata: metadata{clusterDir: clusterDir},
 		steps: []Step{
 			refreshConfigStep,
@@ -74,6 +99,7 @@ func InstallAssetsWorkflow(clusterDir string) Workflow {
 // responsible for running the actions necessary to generate a single bootstrap machine cluster.
 func InstallBootstrapWorkflow(clusterDir string) Workflow {
 	return Workflow{
+		name:     installBootstrapWorkflow,
 		metadata: metadata{clusterDir: clusterDir},
 		steps: []Step{
 			refreshConfigStep,
@@ -90,6 +116,7 @@ func InstallBootstrapWorkflow(clusterDir string) Workflow {
 // responsible for running the actions necessary to scale the machines of the cluster.
 func InstallJoinWorkflow(clusterDir string) Workflow {
 	return Workflow{
+		name:     installJoinWorkflow,
 		metadata: metadata{clusterDir: clusterDir},
 		steps: []Step{
 			refreshConfigStep,
@@ -99,10 +126,52 @@ func InstallJoinWorkflow(clusterDir string) Workflow {
 	}
 }
 
+// ResumeWorkflow creates a new instance of the workflow recorded in the
+// journal of the cluster directory, which continues at its first step that
+// did not finish.
+func ResumeWorkflow(clusterDir string) (Workflow, error) {
+	j, err := readJournal(clusterDir)
+	if err != nil {
+		return Workflow{}, err
+	}
+
+	newWorkflow, ok := resumableWorkflows[j.Workflow]
+	if !ok {
+		return Workflow{}, fmt.Errorf("the %q workflow cannot be resumed", j.Workflow)
+	}
+	w := newWorkflow(clusterDir)
+	if !j.matches(w.steps) {
+		return Workflow{}, fmt.Errorf("the journal at %q does not match the steps of the %q workflow", j.path, j.Workflow)
+	}
+
+	w.from = j.firstIncomplete()
+	if w.from < 0 {
+		return Workflow{}, errors.New("the journaled workflow already finished; nothing to resume")
+	}
+	w.resumed = j
+	return w, nil
+}
+
+// installStepStates maps the install steps to the state they apply.
+var installStepStates = map[string]string{
+	"installTLSAssetsStep":   tlsStep,
+	"installAssetsStep":      assetsStep,
+	"installTopologyStep":    topologyStep,
+	"installTNCCNAMEStep":    tncDNSStep,
+	"installBootstrapStep":   mastersStep,
+	"installTNCARecordStep":  tncDNSStep,
+	"installEtcdStep":        etcdStep,
+	"installJoinMastersStep": mastersStep,
+	"installJoinWorkersStep": joinWorkersStep,
+}
+
 func refreshConfigStep(m *metadata) error {
 	if err := readClusterConfigStep(m); err != nil {
 		return err
 	}
+	if err := checkConfigDrift(m); err != nil {
+		return err
+	}
 	return generateTerraformVariablesStep(m)
 }
 
@@ -150,14 +219,17 @@ func installJoinWorkersStep(m *metadata) error {
 }
 
 func runInstallStep(m *metadata, step string, extraArgs ...string) error {
-	templateDir, err := findStepTemplates(step, m.cluster.Platform)
+	templateDir, err := m.findStepTemplates(step)
 	if err != nil {
 		return err
 	}
-	if err := tfInit(m.clusterDir, templateDir); err != nil {
+	if err := tfInit(m, step, templateDir); err != nil {
 		return err
 	}
-	return tfApply(m.clusterDir, step, templateDir, extraArgs...)
+	if m.plan != nil {
+		return runPlanStep(m, step, templateDir, extraArgs...)
+	}
+	return tfApply(m, step, templateDir, extraArgs...)
 }
 
 func generateIgnConfigStep(m *metadata) error {
@@ -0,0 +1,259 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// initTestClusterDir creates a cluster directory for the aws.basic fixture
+// along with a base directory holding empty step templates. It returns both
+// directories and a function cleaning them up.
+func initTestClusterDir(t *testing.T) (string, string, func()) {
+	dir, err := ioutil.TempDir("", "workflow")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	ps, lic, err := generatePullSecretAndLicense("workflow", time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to generate pull secret and license: %v", err)
+	}
+	cleanup = func() {
+		os.RemoveAll(dir)
+		os.Remove(ps.Name())
+		os.Remove(lic.Name())
+	}
+
+	cluster, err := initTestCluster("./fixtures/aws.basic.yaml", ps.Name(), lic.Name())
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to init cluster: %v", err)
+	}
+
+	clusterDir := filepath.Join(dir, cluster.Name)
+	baseDir := filepath.Join(dir, "base")
+	for _, step := range []string{assetsStep, etcdStep, joinWorkersStep, mastersStep, tlsStep, tncDNSStep, topologyStep} {
+		if err := os.MkdirAll(filepath.Join(baseDir, stepsBaseDir, step), 0755); err != nil {
+			cleanup()
+			t.Fatalf("failed to create %s templates: %v", step, err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(clusterDir, generatedPath, "tls"), 0755); err != nil {
+		cleanup()
+		t.Fatalf("failed to create cluster dir: %v", err)
+	}
+
+	data, err := yaml.Marshal(cluster)
+	if err != nil {
+		cleanup()
+		t.Fatalf("failed to marshal cluster config: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(clusterDir, configFileName), data, 0644); err != nil {
+		cleanup()
+		t.Fatalf("failed to write cluster config: %v", err)
+	}
+	if err := buildInternalConfig(clusterDir); err != nil {
+		cleanup()
+		t.Fatalf("failed to write internal config: %v", err)
+	}
+	// The CA is normally generated by the tls step, and embedded in the ignition configs.
+	if err := writeFile(filepath.Join(clusterDir, generatedPath, "tls", "root-ca.crt"), ""); err != nil {
+		cleanup()
+		t.Fatalf("failed to write root CA: %v", err)
+	}
+
+	return clusterDir, baseDir, cleanup
+}
+
+func TestInstallWorkflowsTerraformInvocations(t *testing.T) {
+	testCases := []struct {
+		test     string
+		workflow func(string) Workflow
+		expected []string
+	}{
+		{
+			test:     "install full",
+			workflow: InstallFullWorkflow,
+			expected: []string{
+				"apply tls",
+				"apply assets",
+				"apply topology",
+				"apply tnc_dns tectonic_bootstrap=true",
+				"apply masters tectonic_bootstrap=true",
+				"apply tnc_dns tectonic_bootstrap=false",
+				"apply etcd",
+				"apply masters tectonic_bootstrap=false",
+				"apply joining_workers",
+			},
+		},
+		{
+			test:     "install join",
+			workflow: InstallJoinWorkflow,
+			expected: []string{
+				"apply masters tectonic_bootstrap=false",
+				"apply joining_workers",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		clusterDir, baseDir, cleanup := initTestClusterDir(t)
+		defer cleanup()
+
+		ex := &fakeExecutor{}
+		w := tc.workflow(clusterDir)
+		w.metadata.baseDir = baseDir
+		w.metadata.executor = ex
+		if err := w.Execute(context.Background()); err != nil {
+			t.Errorf("test case %s: failed to execute workflow: %v", tc.test, err)
+			continue
+		}
+
+		if got := ex.commands("apply"); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("test case %s: expected applies %v, got %v", tc.test, tc.expected, got)
+		}
+		if inits, applies := len(ex.commands("init")), len(tc.expected); inits != applies {
+			t.Errorf("test case %s: expected %d inits, got %d", tc.test, applies, inits)
+		}
+		for _, i := range ex.invocations {
+			if i.dir != clusterDir {
+				t.Errorf("test case %s: expected terraform to run in %s, got %s", tc.test, clusterDir, i.dir)
+			}
+		}
+	}
+}
+
+func TestInstallWorkflowValidatesOnce(t *testing.T) {
+	clusterDir, baseDir, cleanup := initTestClusterDir(t)
+	defer cleanup()
+
+	// The aws.basic fixture stores the admin password in plain text, which
+	// the install full workflow reads in two steps.
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	w := InstallFullWorkflow(clusterDir)
+	w.metadata.baseDir = baseDir
+	w.metadata.executor = &fakeExecutor{}
+	if err := w.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to execute workflow: %v", err)
+	}
+
+	if n := strings.Count(logs.String(), "warning: admin.password:"); n != 1 {
+		t.Errorf("expected the admin password warning once, got %d times:\n%s", n, logs.String())
+	}
+}
+
+func TestInstallWorkflowTerraformFailure(t *testing.T) {
+	clusterDir, baseDir, cleanup := initTestClusterDir(t)
+	defer cleanup()
+
+	ex := &fakeExecutor{
+		fail: func(i invocation) error {
+			if i.command() == "apply" && i.state() == joinWorkersStep {
+				return errors.New("exit status 1")
+			}
+			return nil
+		},
+	}
+	w := InstallJoinWorkflow(clusterDir)
+	w.metadata.baseDir = baseDir
+	w.metadata.executor = ex
+	if err := w.Execute(context.Background()); err == nil {
+		t.Fatal("expected the workflow to fail")
+	}
+
+	if hasStateFile(clusterDir, joinWorkersStep) {
+		t.Errorf("expected no state file for the failed %s step", joinWorkersStep)
+	}
+	j, err := readJournal(clusterDir)
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+	if last := j.Steps[len(j.Steps)-1]; last.Status != stepFailed {
+		t.Errorf("expected the last step to be %s, got %s", stepFailed, last.Status)
+	}
+}
+
+func TestInstallWorkflowWithSteps(t *testing.T) {
+	testCases := []struct {
+		test     string
+		from     string
+		until    string
+		states   []string
+		expected []string
+		err      bool
+	}{
+		{
+			test:     "from etcd",
+			from:     "etcd",
+			states:   []string{tlsStep, assetsStep, topologyStep, tncDNSStep, mastersStep},
+			expected: []string{"apply etcd", "apply masters tectonic_bootstrap=false", "apply joining_workers"},
+		},
+		{
+			test:   "from etcd without the bootstrap states",
+			from:   "installEtcdStep",
+			states: []string{tlsStep, assetsStep},
+			err:    true,
+		},
+		{
+			test:     "until topology",
+			until:    "topology",
+			expected: []string{"apply tls", "apply assets", "apply topology"},
+		},
+		{
+			test:     "from and until masters",
+			from:     "bootstrap",
+			until:    "join-masters",
+			states:   []string{tlsStep, assetsStep, topologyStep, tncDNSStep},
+			expected: []string{"apply masters tectonic_bootstrap=true", "apply tnc_dns tectonic_bootstrap=false", "apply etcd", "apply masters tectonic_bootstrap=false"},
+		},
+		{
+			test: "unknown step",
+			from: "workers",
+			err:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		clusterDir, baseDir, cleanup := initTestClusterDir(t)
+		defer cleanup()
+		for _, state := range tc.states {
+			writeTestState(t, clusterDir, state, nil)
+		}
+
+		ex := &fakeExecutor{}
+		w, err := InstallFullWorkflow(clusterDir).WithSteps(tc.from, tc.until)
+		if (err != nil) != tc.err {
+			t.Errorf("test case %s: expected error %t, got %v", tc.test, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		w.metadata.baseDir = baseDir
+		w.metadata.executor = ex
+		if err := w.Execute(context.Background()); err != nil {
+			t.Errorf("test case %s: failed to execute workflow: %v", tc.test, err)
+			continue
+		}
+
+		if got := ex.commands("apply"); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("test case %s: expected applies %v, got %v", tc.test, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	journalFileName = "journal.json"
+
+	stepPending     stepStatus = "pending"
+	stepStarted     stepStatus = "started"
+	stepFinished    stepStatus = "finished"
+	stepFailed      stepStatus = "failed"
+	stepInterrupted stepStatus = "interrupted"
+	stepSkipped     stepStatus = "skipped"
+)
+
+// stepStatus is the state of a step as recorded in the journal.
+type stepStatus string
+
+// journal records the progress of a workflow in the cluster directory,
+// so that an interrupted or failed workflow can be resumed later on.
+type journal struct {
+	Workflow string         `json:"workflow"`
+	Steps    []journalEntry `json:"steps"`
+
+	path string
+}
+
+// journalEntry is the record of a single step of a workflow.
+type journalEntry struct {
+	Step     string     `json:"step"`
+	Status   stepStatus `json:"status"`
+	Started  *time.Time `json:"started,omitempty"`
+	Finished *time.Time `json:"finished,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// newJournal returns a journal for the given workflow with all steps pending.
+func newJournal(clusterDir, workflow string, steps []Step) *journal {
+	j := &journal{
+		Workflow: workflow,
+		path:     filepath.Join(clusterDir, journalFileName),
+	}
+	for _, step := range steps {
+		j.Steps = append(j.Steps, journalEntry{
+			Step:   stepName(step),
+			Status: stepPending,
+		})
+	}
+	return j
+}
+
+// readJournal loads the journal stored in the given cluster directory.
+func readJournal(clusterDir string) (*journal, error) {
+	path := filepath.Join(clusterDir, journalFileName)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no workflow journal found at %q", path)
+		}
+		return nil, err
+	}
+
+	j := &journal{path: path}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("%s is not a valid journal file: %v", path, err)
+	}
+	return j, nil
+}
+
+// firstIncomplete returns the index of the first step that did not finish,
+// or -1 if every step of the workflow finished. Skipped steps count as
+// finished, as their state was checked when they were skipped.
+func (j *journal) firstIncomplete() int {
+	for i, e := range j.Steps {
+		if e.Status != stepFinished && e.Status != stepSkipped {
+			return i
+		}
+	}
+	return -1
+}
+
+// matches reports whether the journal records the given list of steps.
+func (j *journal) matches(steps []Step) bool {
+	if len(j.Steps) != len(steps) {
+		return false
+	}
+	for i, step := range steps {
+		if j.Steps[i].Step != stepName(step) {
+			return false
+		}
+	}
+	return true
+}
+
+func (j *journal) start(i int) error {
+	now := time.Now().UTC()
+	j.Steps[i].Status = stepStarted
+	j.Steps[i].Started = &now
+	j.Steps[i].Finished = nil
+	j.Steps[i].Error = ""
+	return j.write()
+}
+
+// end records the end of a step with the given status, and the error it
+// failed with, if any.
+func (j *journal) end(i int, status stepStatus, stepErr error) error {
+	now := time.Now().UTC()
+	j.Steps[i].Status = status
+	j.Steps[i].Finished = &now
+	if stepErr != nil {
+		j.Steps[i].Error = stepErr.Error()
+	}
+	return j.write()
+}
+
+func (j *journal) write() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(j.path, string(data)); err != nil {
+		return fmt.Errorf("failed to write workflow journal at %q: %v", j.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package workflow
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+const (
+	configDiffWorkflow        = "config-diff"
+	lastAppliedConfigFileName = "last-applied.yaml"
+)
+
+// ErrImmutableConfigChange is returned when the cluster config changes
+// fields which cannot change since the config was last applied.
+type ErrImmutableConfigChange struct {
+	changes []config.Change
+}
+
+// ErrImmutableConfigChange implements the error interface.
+func (e *ErrImmutableConfigChange) Error() string {
+	var fields []string
+	for _, c := range e.changes {
+		fields = append(fields, fmt.Sprintf("%s (%s -> %s)", c.Path, formatConfigValue(c.Path, c.Old), formatConfigValue(c.Path, c.New)))
+	}
+	return fmt.Sprintf("%s changes fields which cannot change once the cluster is installed: %s; restore them, see the changes with 'tectonic config diff'", configFileName, strings.Join(fields, ", "))
+}
+
+// ConfigDiffWorkflow creates new instances of the 'config diff' workflow,
+// responsible for printing the changes of the cluster config since it was
+// last applied. It does not change anything, so it neither locks nor
+// journals the cluster directory.
+func ConfigDiffWorkflow(clusterDir string) Workflow {
+	return Workflow{
+		name:     configDiffWorkflow,
+		readOnly: true,
+		metadata: metadata{clusterDir: clusterDir},
+		steps: []Step{
+			loadClusterConfigStep,
+			printConfigDiffStep,
+		},
+	}
+}
+
+// writeLastAppliedConfig snapshots the cluster config file of the metadata
+// in the cluster directory, once a step applied it. The snapshot is the file
+// as it was loaded, so it holds neither the values derived from the config
+// nor the values of its references.
+func writeLastAppliedConfig(m *metadata) error {
+	if m.configData == nil {
+		return nil
+	}
+	path := filepath.Join(m.clusterDir, lastAppliedConfigFileName)
+	if err := ioutil.WriteFile(path, m.configData, 0600); err != nil {
+		return fmt.Errorf("failed to write last-applied config at %q: %v", path, err)
+	}
+	return nil
+}
+
+// readLastAppliedConfig returns the cluster config last applied, or nil if
+// it was never applied. The references of the snapshot are resolved as the
+// ones of the cluster config, relative to the cluster directory.
+func readLastAppliedConfig(m *metadata) (*config.Cluster, error) {
+	path := filepath.Join(m.clusterDir, lastAppliedConfigFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, nil
+	}
+	applied, err := config.ParseConfigFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a valid config file: %v", path, err)
+	}
+	return applied, nil
+}
+
+// diffLastAppliedConfig returns the last-applied config, and the changes of
+// the cluster config of the metadata since. Both configs are compared with
+// their derived values, whether or not the steps derived them already.
+// The returned config is nil if the cluster config was never applied.
+func diffLastAppliedConfig(m *metadata) (*config.Cluster, []config.Change, error) {
+	applied, err := readLastAppliedConfig(m)
+	if err != nil || applied == nil {
+		return nil, nil, err
+	}
+	current := m.cluster
+	for _, c := range []*config.Cluster{applied, &current} {
+		if err := c.Derive(); err != nil {
+			return nil, nil, err
+		}
+	}
+	changes, err := config.Diff(applied, &current)
+	return applied, changes, err
+}
+
+// configChanges returns the changes of the cluster config of the metadata
+// since it was last applied. It returns false if it was never applied.
+func configChanges(m *metadata) ([]config.Change, bool, error) {
+	applied, changes, err := diffLastAppliedConfig(m)
+	return changes, applied != nil, err
+}
+
+// checkConfigDrift returns an error if the cluster config of the metadata
+// changes immutable fields since it was last applied, or removes or
+// reorders the worker pools, which are indexed by position.
+func checkConfigDrift(m *metadata) error {
+	applied, changes, err := diffLastAppliedConfig(m)
+	if err != nil || applied == nil {
+		return err
+	}
+	var immutable []config.Change
+	for _, c := range changes {
+		if c.Immutable() {
+			immutable = append(immutable, c)
+		}
+	}
+	if !hasPrefix(m.cluster.Worker.NodePools, applied.Worker.NodePools) {
+		immutable = append(immutable, config.Change{Path: "worker.nodePools", Old: applied.Worker.NodePools, New: m.cluster.Worker.NodePools})
+	}
+	if len(immutable) > 0 {
+		return &ErrImmutableConfigChange{changes: immutable}
+	}
+	return nil
+}
+
+// hasPrefix returns whether the given list starts with the given prefix.
+func hasPrefix(list, prefix []string) bool {
+	if len(list) < len(prefix) {
+		return false
+	}
+	for i := range prefix {
+		if list[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func printConfigDiffStep(m *metadata) error {
+	changes, applied, err := configChanges(m)
+	if err != nil {
+		return err
+	}
+	if !applied {
+		fmt.Fprintln(m.output(), "The config was never applied.")
+		return nil
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(m.output(), "The config did not change since it was last applied.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(m.output(), 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "FIELD\tLAST APPLIED\tCURRENT\tIMMUTABLE")
+	for _, c := range changes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", c.Path, formatConfigValue(c.Path, c.Old), formatConfigValue(c.Path, c.New), yesNo(c.Immutable()))
+	}
+	return w.Flush()
+}
+
+// formatConfigValue formats the value of a config field for display,
+// hiding the admin password.
+func formatConfigValue(path string, v interface{}) string {
+	switch {
+	case v == nil:
+		return "<unset>"
+	case path == "admin.password":
+		return "<hidden>"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,166 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+// addWorkerPool returns the config change adding a gpu node pool, and
+// setting the worker pools to the given ones.
+func addWorkerPool(pools []string) func(c *config.Cluster) {
+	return func(c *config.Cluster) {
+		c.NodePools = append(c.NodePools, config.NodePool{Name: "gpu", Count: 1})
+		c.Worker.NodePools = pools
+	}
+}
+
+func TestInstallWorkflowConfigDrift(t *testing.T) {
+	testCases := []struct {
+		test   string
+		change func(c *config.Cluster)
+		err    bool
+	}{
+		{
+			test:   "node pool count",
+			change: func(c *config.Cluster) { c.NodePools[2].Count++ },
+		},
+		{
+			test:   "base domain",
+			change: func(c *config.Cluster) { c.BaseDomain = "changed.example.com" },
+			err:    true,
+		},
+		{
+			test:   "service CIDR",
+			change: func(c *config.Cluster) { c.Networking.ServiceCIDR = "10.4.0.0/16" },
+			err:    true,
+		},
+		{
+			test:   "append worker pool",
+			change: addWorkerPool([]string{"worker", "gpu"}),
+		},
+		{
+			test:   "prepend worker pool",
+			change: addWorkerPool([]string{"gpu", "worker"}),
+			err:    true,
+		},
+		{
+			test:   "replace worker pool",
+			change: addWorkerPool([]string{"gpu"}),
+			err:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		clusterDir, baseDir, cleanup := initTestClusterDir(t)
+		defer cleanup()
+
+		w := InstallJoinWorkflow(clusterDir)
+		w.metadata.baseDir = baseDir
+		w.metadata.executor = &fakeExecutor{}
+		if err := w.Execute(context.Background()); err != nil {
+			t.Errorf("test case %s: failed to execute workflow: %v", tc.test, err)
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(clusterDir, lastAppliedConfigFileName)); err != nil {
+			t.Errorf("test case %s: expected a last-applied config: %v", tc.test, err)
+			continue
+		} else if mode := info.Mode().Perm(); mode != 0600 {
+			t.Errorf("test case %s: expected the last-applied config to only be readable by its owner, got mode %v", tc.test, mode)
+		}
+
+		configFilePath := filepath.Join(clusterDir, configFileName)
+		cluster, err := config.ParseConfigFile(configFilePath)
+		if err != nil {
+			t.Fatalf("failed to read cluster config: %v", err)
+		}
+		tc.change(cluster)
+		data, err := yaml.Marshal(cluster)
+		if err != nil {
+			t.Fatalf("failed to marshal cluster config: %v", err)
+		}
+		if err := ioutil.WriteFile(configFilePath, data, 0644); err != nil {
+			t.Fatalf("failed to write cluster config: %v", err)
+		}
+
+		w = InstallJoinWorkflow(clusterDir)
+		w.metadata.baseDir = baseDir
+		w.metadata.executor = &fakeExecutor{}
+		err = w.Execute(context.Background())
+		if _, ok := err.(*ErrImmutableConfigChange); ok != tc.err {
+			t.Errorf("test case %s: expected immutable change error %t, got %v", tc.test, tc.err, err)
+		}
+	}
+}
+
+func TestLastAppliedConfigLibvirt(t *testing.T) {
+	clusterDir, err := ioutil.TempDir("", "lastapplied")
+	if err != nil {
+		t.Fatalf("failed to create cluster dir: %v", err)
+	}
+	defer os.RemoveAll(clusterDir)
+
+	files := map[string]string{
+		"admin-password": "secret-password\n",
+		internalFileName: "",
+		configFileName: `admin:
+  email: fake-email@example.com
+  password: ${file:admin-password}
+baseDomain: example.com
+libvirt:
+  network:
+    ipRange: 192.168.124.0/24
+master:
+  nodePools: [master]
+name: libvirt
+nodePools:
+  - name: master
+    count: 2
+platform: libvirt
+`,
+	}
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(clusterDir, name), []byte(data), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	m := &metadata{clusterDir: clusterDir}
+	if err := loadClusterConfigStep(m); err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+	// Generating the variables derives the master IPs.
+	if _, err := m.cluster.TFVars(); err != nil {
+		t.Fatalf("failed to generate variables: %v", err)
+	}
+	if err := writeLastAppliedConfig(m); err != nil {
+		t.Fatalf("failed to write last-applied config: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(clusterDir, lastAppliedConfigFileName))
+	if err != nil {
+		t.Fatalf("failed to read last-applied config: %v", err)
+	}
+	if bytes.Contains(data, []byte("secret-password")) {
+		t.Errorf("expected the last-applied config to keep the admin password reference, got:\n%s", data)
+	}
+
+	m = &metadata{clusterDir: clusterDir}
+	if err := loadClusterConfigStep(m); err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+	changes, applied, err := configChanges(m)
+	if err != nil {
+		t.Fatalf("failed to diff the config: %v", err)
+	}
+	if !applied || len(changes) != 0 {
+		t.Errorf("expected no changes since the config was applied, got %v", changes)
+	}
+}
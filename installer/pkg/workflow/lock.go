@@ -0,0 +1,111 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const lockFileName = "tectonic.lock"
+
+// lockInfo describes the holder of the lock of a cluster directory.
+type lockInfo struct {
+	Owner   string    `json:"owner"`
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+	Command string    `json:"command"`
+	Created time.Time `json:"created"`
+}
+
+// ErrClusterLocked is returned when a workflow is started on a cluster
+// directory another workflow holds the lock of.
+type ErrClusterLocked struct {
+	path string
+	info lockInfo
+}
+
+// ErrClusterLocked implements the error interface.
+func (e *ErrClusterLocked) Error() string {
+	return fmt.Sprintf("cluster directory is locked by %q (pid %d on %s, since %s) running %q; if no other workflow is running, remove the stale lock at %s with --force-unlock",
+		e.info.Owner, e.info.PID, e.info.Host, e.info.Created.Format(time.RFC3339), e.info.Command, e.path)
+}
+
+// acquireLock creates the lock file of the given cluster directory, and
+// returns a function releasing it. It fails if the lock is already held.
+func acquireLock(clusterDir string) (func(), error) {
+	path := filepath.Join(clusterDir, lockFileName)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file at %q: %v", path, err)
+		}
+		info, err := readLock(path)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &ErrClusterLocked{path: path, info: *info}
+	}
+	defer f.Close()
+
+	info := currentLockInfo()
+	if err := json.NewEncoder(f).Encode(info); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to write lock file at %q: %v", path, err)
+	}
+
+	return func() {
+		if err := os.Remove(path); err != nil {
+			log.Warnf("failed to remove lock file at %q: %v", path, err)
+		}
+	}, nil
+}
+
+// forceUnlock removes the lock file of the given cluster directory, if any.
+func forceUnlock(clusterDir string) error {
+	path := filepath.Join(clusterDir, lockFileName)
+	info, err := readLock(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		log.Warnf("removing unreadable lock file at %q: %v", path, err)
+	} else {
+		log.Warnf("removing lock held by %q (pid %d on %s) running %q", info.Owner, info.PID, info.Host, info.Command)
+	}
+	return os.Remove(path)
+}
+
+func readLock(path string) (*lockInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info := &lockInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("%s is not a valid lock file: %v", path, err)
+	}
+	return info, nil
+}
+
+func currentLockInfo() lockInfo {
+	info := lockInfo{
+		Owner:   os.Getenv("USER"),
+		PID:     os.Getpid(),
+		Command: strings.Join(os.Args, " "),
+		Created: time.Now().UTC(),
+	}
+	if u, err := user.Current(); err == nil {
+		info.Owner = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		info.Host = host
+	}
+	return info
+}
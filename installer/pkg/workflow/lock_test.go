@@ -0,0 +1,53 @@
+package workflow
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkflowLock(t *testing.T) {
+	clusterDir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatalf("failed to create cluster dir: %v", err)
+	}
+	defer os.RemoveAll(clusterDir)
+	lockFilePath := filepath.Join(clusterDir, lockFileName)
+
+	var locked bool
+	wf := Workflow{
+		metadata: metadata{clusterDir: clusterDir},
+		steps: []Step{
+			func(*metadata) error {
+				_, err := os.Stat(lockFilePath)
+				locked = err == nil
+				return nil
+			},
+		},
+	}
+	if err := wf.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to execute workflow: %v", err)
+	}
+	if !locked {
+		t.Error("expected the cluster dir to be locked while the workflow runs")
+	}
+	if _, err := os.Stat(lockFilePath); !os.IsNotExist(err) {
+		t.Errorf("expected the lock to be released, got: %v", err)
+	}
+
+	// The lock is left behind, like the lock of a crashed workflow.
+	if _, err := acquireLock(clusterDir); err != nil {
+		t.Fatalf("failed to acquire lock: %v", err)
+	}
+
+	err = wf.Execute(context.Background())
+	if _, ok := err.(*ErrClusterLocked); !ok {
+		t.Errorf("expected an ErrClusterLocked, got: %v", err)
+	}
+
+	if err := wf.WithForceUnlock().Execute(context.Background()); err != nil {
+		t.Errorf("failed to execute workflow with force unlock: %v", err)
+	}
+}
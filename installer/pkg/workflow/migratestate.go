@@ -0,0 +1,244 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+// legacyStateName is the name of the copy of the legacy state in the
+// cluster directory, holding the resources left to migrate.
+const legacyStateName = "legacy"
+
+// legacyStateMove moves the resources of a legacy monolithic state at the
+// given address, and below it, to the state of a step.
+type legacyStateMove struct {
+	step string
+	from string
+	// to is the address of the resources in the state of the step, when it
+	// differs from their legacy address.
+	to string
+}
+
+// legacyStateMoves lists the resources of the legacy monolithic states and
+// the steps now managing them.
+var legacyStateMoves = []legacyStateMove{
+	{step: tlsStep, from: "module.ca_certs"},
+	{step: tlsStep, from: "module.etcd_certs"},
+	{step: tlsStep, from: "module.ingress_certs"},
+	{step: tlsStep, from: "module.kube_certs"},
+	{step: tlsStep, from: "module.tnc_certs"},
+
+	{step: assetsStep, from: "module.bootkube", to: "module.assets_base.module.bootkube"},
+	{step: assetsStep, from: "module.tectonic", to: "module.assets_base.module.tectonic"},
+	{step: assetsStep, from: "module.ignition_bootstrap", to: "module.assets_base.module.ignition_bootstrap"},
+	{step: assetsStep, from: "local_file.kubeconfig", to: "module.assets_base.local_file.kubeconfig"},
+	{step: assetsStep, from: "local_file.kubeconfig-kubelet", to: "module.assets_base.local_file.kubeconfig-kubelet"},
+
+	{step: topologyStep, from: "module.vpc"},
+	{step: topologyStep, from: "module.dns"},
+	{step: topologyStep, from: "aws_route53_zone.tectonic_int"},
+	{step: topologyStep, from: "aws_s3_bucket.tectonic"},
+	{step: topologyStep, from: "aws_s3_bucket_object.ignition_bootstrap"},
+	{step: topologyStep, from: "aws_s3_bucket_object.ignition_bootstrap_real"},
+	{step: topologyStep, from: "module.libvirt_base_volume"},
+	{step: topologyStep, from: "libvirt_network.tectonic_net"},
+	{step: topologyStep, from: "null_resource.console_dns"},
+
+	{step: tncDNSStep, from: "aws_route53_record.tectonic_tnc_a"},
+	{step: tncDNSStep, from: "aws_route53_record.tectonic_tnc_cname"},
+	{step: tncDNSStep, from: "null_resource.tnc_dns"},
+
+	{step: etcdStep, from: "module.etcd"},
+	{step: etcdStep, from: "aws_route53_record.etcd_a_nodes"},
+	{step: etcdStep, from: "aws_s3_bucket_object.ignition_etcd"},
+	{step: etcdStep, from: "libvirt_domain.etcd"},
+	{step: etcdStep, from: "libvirt_ignition.etcd"},
+	{step: etcdStep, from: "libvirt_volume.etcd"},
+
+	{step: mastersStep, from: "module.masters"},
+	{step: mastersStep, from: "libvirt_domain.master"},
+	{step: mastersStep, from: "libvirt_ignition.master"},
+	{step: mastersStep, from: "libvirt_ignition.master_bootstrap"},
+	{step: mastersStep, from: "libvirt_volume.master"},
+
+	{step: joinWorkersStep, from: "module.workers"},
+	{step: joinWorkersStep, from: "libvirt_domain.worker"},
+	{step: joinWorkersStep, from: "libvirt_ignition.worker"},
+	{step: joinWorkersStep, from: "libvirt_volume.worker"},
+}
+
+// matches returns whether the resource at the given address is moved.
+func (mv legacyStateMove) matches(address string) bool {
+	return address == mv.from || strings.HasPrefix(address, mv.from+".")
+}
+
+// planLegacyStateMoves returns the moves of the resources of the given
+// legacy state, in the order of legacyStateMoves. It fails if any resource
+// belongs to no step, since it would be left out of every state.
+func planLegacyStateMoves(state *tfState) ([]legacyStateMove, error) {
+	used := make(map[int]bool)
+	var unknown []string
+	for _, r := range state.resources() {
+		found := false
+		for i, mv := range legacyStateMoves {
+			if mv.matches(r.Address) {
+				used[i] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, r.Address)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("the legacy state has resources of no step, remove them with 'terraform state rm' first: %s", strings.Join(unknown, ", "))
+	}
+
+	var moves []legacyStateMove
+	for i, mv := range legacyStateMoves {
+		if used[i] {
+			moves = append(moves, mv)
+		}
+	}
+	return moves, nil
+}
+
+// MigrateStateWorkflow creates new instances of the 'migrate-state'
+// workflow, responsible for moving a cluster installed with the legacy
+// monolithic layout, from its tfvars and single state file, into a cluster
+// directory with a state per step. The legacy state file is left untouched.
+func MigrateStateWorkflow(tfvarsFilePath, stateFilePath string) Workflow {
+	return Workflow{
+		metadata: metadata{configFilePath: tfvarsFilePath},
+		steps: []Step{
+			readLegacyTFVarsStep,
+			prepareMigratedClusterStep(stateFilePath),
+			splitLegacyStateStep,
+			generateTerraformVariablesStep,
+		},
+	}
+}
+
+// readLegacyTFVarsStep reads the tfvars of the legacy cluster. Its cluster
+// ID is kept in the internal config rather than converted.
+func readLegacyTFVarsStep(m *metadata) error {
+	return readTFVarsConfig(m, map[string]bool{"tectonic_cluster_id": true})
+}
+
+// prepareMigratedClusterStep returns the step creating the cluster
+// directory of the legacy cluster, with its config, its internal config
+// keeping the cluster ID and a copy of the given legacy state.
+func prepareMigratedClusterStep(statePath string) Step {
+	return func(m *metadata) error {
+		if m.cluster.ClusterID == "" {
+			return errors.New("the tfvars have no tectonic_cluster_id, which the resources of the cluster are tagged with")
+		}
+		state, err := readState(statePath)
+		if err != nil {
+			return err
+		}
+		if _, err := planLegacyStateMoves(state); err != nil {
+			return err
+		}
+
+		dir, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %v", err)
+		}
+		clusterDir := filepath.Join(dir, m.cluster.Name)
+
+		// The admin password moves out of the config, as with init.
+		password := m.cluster.Admin.Password
+		if password != "" {
+			m.cluster.Admin.Password = ""
+			m.cluster.Admin.PasswordFrom = &config.SecretRef{File: filepath.Join(clusterDir, adminPasswordFileName)}
+		}
+		// The node counts of the tfvars become node pools of the config.
+		data, err := m.cluster.YAML()
+		if err != nil {
+			return err
+		}
+		cluster, err := config.ParseConfig([]byte(data))
+		if err != nil {
+			return fmt.Errorf("%s was converted to an invalid config: %v", m.configFilePath, err)
+		}
+		cluster.Internal = m.cluster.Internal
+		m.cluster = *cluster
+		if err := validateClusterConfig(m, &m.cluster); err != nil {
+			return err
+		}
+
+		m.clusterDir = clusterDir
+		if stat, err := os.Stat(clusterDir); err == nil && stat.IsDir() {
+			return fmt.Errorf("cluster directory already exists at %q", clusterDir)
+		}
+		if err := os.MkdirAll(clusterDir, os.ModeDir|0755); err != nil {
+			return fmt.Errorf("failed to create cluster directory at %q", clusterDir)
+		}
+
+		if password != "" {
+			if err := writeAdminPassword(filepath.Join(clusterDir, adminPasswordFileName), password); err != nil {
+				return err
+			}
+		}
+		header := fmt.Sprintf("# The config of the cluster, converted from %s by 'tectonic migrate-state'.\n", m.configFilePath)
+		if err := writeFile(filepath.Join(clusterDir, configFileName), header+data); err != nil {
+			return fmt.Errorf("failed to create cluster config at %q: %v", clusterDir, err)
+		}
+		if err := writeInternalConfig(clusterDir, m.cluster.Internal); err != nil {
+			return err
+		}
+		log.Infof("Kept the cluster ID %s in %s", m.cluster.ClusterID, filepath.Join(clusterDir, internalFileName))
+
+		return copyFile(statePath, stateFilePath(clusterDir, legacyStateName))
+	}
+}
+
+// splitLegacyStateStep moves the resources of the copy of the legacy state
+// to the states of their steps, and removes the emptied copy. A failed
+// migration is retried from the legacy state file after removing the
+// cluster directory.
+func splitLegacyStateStep(m *metadata) error {
+	state, err := readStateFile(m.clusterDir, legacyStateName)
+	if err != nil {
+		return err
+	}
+	moves, err := planLegacyStateMoves(state)
+	if err != nil {
+		return err
+	}
+
+	for _, mv := range moves {
+		to := mv.to
+		if to == "" {
+			to = mv.from
+		}
+		args := []string{
+			"state",
+			"mv",
+			fmt.Sprintf("-state=%s.tfstate", legacyStateName),
+			fmt.Sprintf("-state-out=%s.tfstate", mv.step),
+			mv.from,
+			to,
+		}
+		if err := terraformExec(m, mv.step, nil, args...); err != nil {
+			return err
+		}
+		log.Infof("Moved %s to the %s state", mv.from, mv.step)
+	}
+
+	// The copy holds no resources anymore, and would otherwise be left next
+	// to the states of the steps.
+	if err := os.Remove(stateFilePath(m.clusterDir, legacyStateName)); err != nil {
+		return fmt.Errorf("failed to remove the copy of the legacy state: %v", err)
+	}
+	return nil
+}
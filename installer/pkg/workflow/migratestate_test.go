@@ -0,0 +1,195 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// writeLegacyState writes a legacy monolithic state at the given path,
+// holding the given resources by module.
+func writeLegacyState(t *testing.T, path string, modules map[string][]string) {
+	state := tfState{Version: 3}
+	for module, addresses := range modules {
+		m := tfStateModule{Path: []string{"root"}, Resources: make(map[string]tfStateResource)}
+		if module != "" {
+			m.Path = append(m.Path, strings.Split(module, ".")...)
+		}
+		for _, address := range addresses {
+			m.Resources[address] = testResource(strings.Split(address, ".")[0], address, nil)
+		}
+		state.Modules = append(state.Modules, m)
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("failed to marshal legacy state: %v", err)
+	}
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to write legacy state: %v", err)
+	}
+}
+
+func TestMigrateStateWorkflow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "migrate")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	ps, lic, err := generatePullSecretAndLicense("migrate", time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("failed to generate pull secret and license: %v", err)
+	}
+	defer os.Remove(ps.Name())
+	defer os.Remove(lic.Name())
+
+	tfvars, err := json.Marshal(map[string]interface{}{
+		"tectonic_admin_email":      "admin@example.com",
+		"tectonic_admin_password":   "fake-password",
+		"tectonic_aws_region":       "eu-west-1",
+		"tectonic_aws_ssh_key":      "core",
+		"tectonic_base_domain":      "example.com",
+		"tectonic_cluster_id":       "0123456789abcdef",
+		"tectonic_cluster_name":     "legacy",
+		"tectonic_etcd_count":       "3",
+		"tectonic_license_path":     lic.Name(),
+		"tectonic_master_count":     "2",
+		"tectonic_platform":         "aws",
+		"tectonic_pull_secret_path": ps.Name(),
+		"tectonic_worker_count":     "3",
+	})
+	if err != nil {
+		t.Fatalf("failed to marshal tfvars: %v", err)
+	}
+	tfvarsPath := filepath.Join(dir, "terraform.tfvars")
+	if err := ioutil.WriteFile(tfvarsPath, tfvars, 0644); err != nil {
+		t.Fatalf("failed to write tfvars: %v", err)
+	}
+
+	testCases := []struct {
+		test     string
+		modules  map[string][]string
+		expected []string
+		err      bool
+	}{
+		{
+			test: "aws",
+			modules: map[string][]string{
+				"":           {"aws_route53_record.etcd_a_nodes.0", "aws_s3_bucket.tectonic", "local_file.kubeconfig"},
+				"kube_certs": {"tls_private_key.kube_ca"},
+				"bootkube":   {"local_file.kubeconfig"},
+				"vpc":        {"aws_vpc.cluster_vpc"},
+				"etcd":       {"aws_instance.etcd_node.0", "aws_instance.etcd_node.1", "aws_instance.etcd_node.2"},
+				"masters":    {"aws_autoscaling_group.masters"},
+				"workers":    {"aws_autoscaling_group.workers"},
+			},
+			expected: []string{
+				"tls module.kube_certs module.kube_certs",
+				"assets module.bootkube module.assets_base.module.bootkube",
+				"assets local_file.kubeconfig module.assets_base.local_file.kubeconfig",
+				"topology module.vpc module.vpc",
+				"topology aws_s3_bucket.tectonic aws_s3_bucket.tectonic",
+				"etcd module.etcd module.etcd",
+				"etcd aws_route53_record.etcd_a_nodes aws_route53_record.etcd_a_nodes",
+				"masters module.masters module.masters",
+				"joining_workers module.workers module.workers",
+			},
+		},
+		{
+			test: "unknown resource",
+			modules: map[string][]string{
+				"":     {"aws_s3_bucket.tectonic", "null_resource.custom"},
+				"etcd": {"aws_instance.etcd_node.0"},
+			},
+			err: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		clusterDir := filepath.Join(dir, "legacy")
+		statePath := filepath.Join(dir, "terraform.tfstate")
+		writeLegacyState(t, statePath, tc.modules)
+		original, err := ioutil.ReadFile(statePath)
+		if err != nil {
+			t.Fatalf("failed to read legacy state: %v", err)
+		}
+
+		var logs bytes.Buffer
+		log.SetOutput(&logs)
+		ex := &fakeExecutor{}
+		w := MigrateStateWorkflow(tfvarsPath, statePath)
+		w.metadata.executor = ex
+		err = w.Execute(context.Background())
+		log.SetOutput(os.Stderr)
+		if (err != nil) != tc.err {
+			t.Errorf("test case %s: expected error %t, got %v", tc.test, tc.err, err)
+		}
+		if strings.Contains(logs.String(), "tectonic_cluster_id") {
+			t.Errorf("test case %s: expected no warning about the kept cluster ID, got:\n%s", tc.test, logs.String())
+		}
+
+		var moves []string
+		for _, i := range ex.invocations {
+			if i.command() != "state" || i.args[1] != "mv" || i.args[2] != "-state=legacy.tfstate" {
+				t.Errorf("test case %s: unexpected invocation %v", tc.test, i.args)
+				continue
+			}
+			step := strings.TrimSuffix(strings.TrimPrefix(i.args[3], "-state-out="), ".tfstate")
+			moves = append(moves, strings.Join(append([]string{step}, i.args[4:]...), " "))
+		}
+		if !reflect.DeepEqual(moves, tc.expected) {
+			t.Errorf("test case %s: expected moves %v, got %v", tc.test, tc.expected, moves)
+		}
+
+		if data, err := ioutil.ReadFile(statePath); err != nil || string(data) != string(original) {
+			t.Errorf("test case %s: expected the legacy state to be left untouched", tc.test)
+		}
+
+		if tc.err {
+			if _, err := os.Stat(clusterDir); !os.IsNotExist(err) {
+				t.Errorf("test case %s: expected no cluster directory, got %v", tc.test, err)
+			}
+			continue
+		}
+		cluster, err := readClusterConfig(filepath.Join(clusterDir, configFileName), filepath.Join(clusterDir, internalFileName))
+		if err != nil {
+			t.Errorf("test case %s: failed to read the migrated config: %v", tc.test, err)
+		} else {
+			if cluster.ClusterID != "0123456789abcdef" {
+				t.Errorf("test case %s: expected the cluster ID to be kept, got %q", tc.test, cluster.ClusterID)
+			}
+			if cluster.Admin.Password != "" || cluster.Admin.PasswordFrom == nil {
+				t.Errorf("test case %s: expected the admin password to be referenced, got %+v", tc.test, cluster.Admin)
+			}
+			if errs := cluster.Validate(); len(errs) != 0 {
+				t.Errorf("test case %s: expected a valid config, got %v", tc.test, errs)
+			}
+		}
+		for _, file := range []string{adminPasswordFileName, terraformVariablesFileName} {
+			if _, err := os.Stat(filepath.Join(clusterDir, file)); err != nil {
+				t.Errorf("test case %s: expected %s in the cluster directory: %v", tc.test, file, err)
+			}
+		}
+		if _, err := os.Stat(stateFilePath(clusterDir, legacyStateName)); !os.IsNotExist(err) {
+			t.Errorf("test case %s: expected the copy of the legacy state to be removed, got %v", tc.test, err)
+		}
+		os.RemoveAll(clusterDir)
+	}
+}
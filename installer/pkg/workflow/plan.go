@@ -0,0 +1,166 @@
+package workflow
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+var (
+	planChangesRegexp = regexp.MustCompile(`Plan: (\d+) to add, (\d+) to change, (\d+) to destroy`)
+	ansiEscapeRegexp  = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// planSummary collects the plans of all terraform steps of a workflow.
+type planSummary struct {
+	steps []stepPlan
+}
+
+// stepPlan is the outcome of planning a single terraform step.
+type stepPlan struct {
+	step    string
+	args    []string
+	add     int
+	change  int
+	destroy int
+	err     error
+}
+
+// PlanFullWorkflow creates new instances of the 'plan' workflow for the
+// 'install' workflow. It walks the same steps, but only plans the terraform
+// steps instead of applying them.
+func PlanFullWorkflow(clusterDir string) Workflow {
+	return planWorkflow(InstallFullWorkflow(clusterDir))
+}
+
+// PlanJoinWorkflow creates new instances of the 'plan' workflow for the
+// 'join' workflow.
+func PlanJoinWorkflow(clusterDir string) Workflow {
+	return planWorkflow(InstallJoinWorkflow(clusterDir))
+}
+
+// planWorkflow turns the given install workflow into a plan workflow.
+// Plan workflows do not change the cluster directory: they run against a
+// temporary copy of it, which receives the files the steps generate. Only
+// the output of TerraForm is appended to the logs of the cluster directory,
+// so that it outlives the copy. They are neither locked nor journaled, as
+// they must not interfere with the install they are planning.
+func planWorkflow(w Workflow) Workflow {
+	w.name = ""
+	w.readOnly = true
+	w.metadata.plan = &planSummary{}
+	// The first step of the install workflows refreshes the config.
+	w.steps = append([]Step{preparePlanWorkspaceStep}, w.steps[1:]...)
+	w.steps = append(w.steps, printPlanSummaryStep)
+	return w
+}
+
+// preparePlanWorkspaceStep copies the cluster directory into a temporary
+// directory, removed once the workflow finished, and refreshes the config
+// of the copy the plan runs against. TerraForm keeps logging to the logs of
+// the cluster directory.
+func preparePlanWorkspaceStep(m *metadata) error {
+	dir, err := ioutil.TempDir("", "tectonic-plan")
+	if err != nil {
+		return fmt.Errorf("failed to create the plan directory: %v", err)
+	}
+	m.cleanups = append(m.cleanups, func() { os.RemoveAll(dir) })
+
+	workDir := filepath.Join(dir, filepath.Base(m.clusterDir))
+	if err := copyDir(m.clusterDir, workDir); err != nil {
+		return fmt.Errorf("failed to copy the cluster directory: %v", err)
+	}
+	m.logsDir = filepath.Join(m.clusterDir, logsPath)
+	m.clusterDir = workDir
+	return refreshConfigStep(m)
+}
+
+func runPlanStep(m *metadata, step, templateDir string, extraArgs ...string) error {
+	p := stepPlan{
+		step: step,
+		args: extraArgs,
+	}
+
+	changes, out, err := tfPlan(m, step, templateDir, extraArgs...)
+	if err != nil && m.ctx.Err() != nil {
+		return err
+	}
+	if err != nil {
+		// Keep planning the remaining steps and report the failure in the summary.
+		p.err = err
+	} else if changes {
+		p.add, p.change, p.destroy, p.err = parsePlanChanges(out)
+	}
+	m.plan.steps = append(m.plan.steps, p)
+
+	return nil
+}
+
+// parsePlanChanges extracts the number of resources to add, change and
+// destroy from the output of 'terraform plan'.
+func parsePlanChanges(out string) (int, int, int, error) {
+	match := planChangesRegexp.FindStringSubmatch(ansiEscapeRegexp.ReplaceAllString(out, ""))
+	if match == nil {
+		return 0, 0, 0, fmt.Errorf("no plan summary found in terraform output")
+	}
+
+	var counts [3]int
+	for i := range counts {
+		n, err := strconv.Atoi(match[i+1])
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		counts[i] = n
+	}
+	return counts[0], counts[1], counts[2], nil
+}
+
+func printPlanSummaryStep(m *metadata) error {
+	var failed int
+	var add, change, destroy int
+
+	w := tabwriter.NewWriter(m.output(), 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tADD\tCHANGE\tDESTROY\t")
+	for _, p := range m.plan.steps {
+		name := p.step
+		if len(p.args) > 0 {
+			name = fmt.Sprintf("%s (%s)", p.step, strings.Join(p.args, " "))
+		}
+		if p.err != nil {
+			failed++
+			fmt.Fprintf(w, "%s\terror: %s\t\t\t\n", name, planErrorMessage(p.err))
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t\n", name, p.add, p.change, p.destroy)
+		add += p.add
+		change += p.change
+		destroy += p.destroy
+	}
+	fmt.Fprintf(w, "total\t%d\t%d\t%d\t\n", add, change, destroy)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to plan %d step(s)", failed)
+	}
+	return nil
+}
+
+// planErrorMessage returns a single line describing why a step failed to plan.
+func planErrorMessage(err error) string {
+	tfErr, ok := err.(*ErrTerraform)
+	if !ok {
+		return err.Error()
+	}
+	msg := tfErr.Err.Error()
+	if tfErr.Summary != "" {
+		msg = strings.SplitN(tfErr.Summary, "\n", 2)[0]
+	}
+	return fmt.Sprintf("%s (see %s)", msg, tfErr.LogFile)
+}
@@ -0,0 +1,171 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanWorkflow(t *testing.T) {
+	clusterDir, baseDir, cleanup := initTestClusterDir(t)
+	defer cleanup()
+	writeTestState(t, clusterDir, mastersStep, map[string]tfStateResource{
+		"aws_autoscaling_group.masters": testResource("aws_autoscaling_group", "masters", map[string]string{"desired_capacity": "2"}),
+	})
+	before := snapshotDir(t, clusterDir)
+
+	// TerraForm exits with 2 when the plan has changes.
+	changes := exec.Command("sh", "-c", "exit 2").Run()
+	if _, ok := changes.(*exec.ExitError); !ok {
+		t.Fatalf("failed to get an exit status of 2: %v", changes)
+	}
+	ex := &fakeExecutor{
+		output: func(i invocation) string {
+			if i.command() == "plan" && i.state() == mastersStep {
+				return "Plan: 1 to add, 2 to change, 0 to destroy.\n"
+			}
+			return ""
+		},
+		fail: func(i invocation) error {
+			if i.command() == "plan" && i.state() == mastersStep {
+				return changes
+			}
+			return nil
+		},
+	}
+	var out bytes.Buffer
+	w := PlanFullWorkflow(clusterDir)
+	w.metadata.baseDir = baseDir
+	w.metadata.executor = ex
+	w.metadata.out = &out
+	if err := w.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to execute workflow: %v", err)
+	}
+
+	rows := make(map[string][]string)
+	for _, line := range strings.Split(out.String(), "\n") {
+		if i := strings.LastIndex(line, ")"); i >= 0 {
+			rows[line[:i+1]] = strings.Fields(line[i+1:])
+		} else if fields := strings.Fields(line); len(fields) > 0 {
+			rows[fields[0]] = fields[1:]
+		}
+	}
+	expected := map[string][]string{
+		"masters (-var=tectonic_bootstrap=true)":  {"1", "2", "0"},
+		"masters (-var=tectonic_bootstrap=false)": {"1", "2", "0"},
+		"etcd":  {"0", "0", "0"},
+		"total": {"2", "4", "0"},
+	}
+	for row, counts := range expected {
+		if !reflect.DeepEqual(rows[row], counts) {
+			t.Errorf("expected %s to plan %v, got %v in:\n%s", row, counts, rows[row], out.String())
+		}
+	}
+
+	// Only the logs of the cluster directory change.
+	after := snapshotDir(t, clusterDir)
+	for path := range after {
+		if strings.HasPrefix(path, filepath.Join(clusterDir, logsPath)+string(filepath.Separator)) {
+			delete(after, path)
+		}
+	}
+	if !reflect.DeepEqual(after, before) {
+		t.Errorf("expected the cluster directory to be unchanged, got %v instead of %v", keys(after), keys(before))
+	}
+	for _, i := range ex.invocations {
+		if i.dir == clusterDir {
+			t.Errorf("expected terraform to run in a copy of the cluster directory, got %v", i.args)
+		}
+		if _, err := os.Stat(i.dir); !os.IsNotExist(err) {
+			t.Errorf("expected the copy of the cluster directory to be removed, got %v", err)
+			break
+		}
+	}
+}
+
+func TestPlanWorkflowErrorLog(t *testing.T) {
+	clusterDir, baseDir, cleanup := initTestClusterDir(t)
+	defer cleanup()
+
+	ex := &fakeExecutor{
+		output: func(i invocation) string {
+			if i.command() == "plan" && i.state() == etcdStep {
+				return "Error: etcd failed to plan\n"
+			}
+			return ""
+		},
+		fail: func(i invocation) error {
+			if i.command() == "plan" && i.state() == etcdStep {
+				return errors.New("exit status 1")
+			}
+			return nil
+		},
+	}
+	var out bytes.Buffer
+	w := PlanFullWorkflow(clusterDir)
+	w.metadata.baseDir = baseDir
+	w.metadata.executor = ex
+	w.metadata.out = &out
+	if err := w.Execute(context.Background()); err == nil {
+		t.Fatalf("expected the workflow to fail, got:\n%s", out.String())
+	}
+
+	logFile := filepath.Join(clusterDir, logsPath, "installEtcdStep.log")
+	if !strings.Contains(out.String(), "(see "+logFile+")") {
+		t.Errorf("expected the summary to point to %s, got:\n%s", logFile, out.String())
+	}
+	data, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("expected the plan log to outlive the workflow: %v", err)
+	}
+	if !strings.Contains(string(data), "etcd failed to plan") {
+		t.Errorf("expected the plan log to hold the output of terraform, got:\n%s", data)
+	}
+}
+
+func TestParsePlanChanges(t *testing.T) {
+	testCases := []struct {
+		test          string
+		output        string
+		add           int
+		change        int
+		destroy       int
+		expectedError bool
+	}{
+		{
+			test:    "plain output",
+			output:  "aws_instance.master: Refreshing state...\n\nPlan: 3 to add, 1 to change, 2 to destroy.\n",
+			add:     3,
+			change:  1,
+			destroy: 2,
+		},
+		{
+			test:   "colored output",
+			output: "\x1b[0m\x1b[1mPlan:\x1b[0m 5 to add, 0 to change, 0 to destroy.\x1b[0m\n",
+			add:    5,
+		},
+		{
+			test:          "no summary",
+			output:        "Error: Error refreshing state\n",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		add, change, destroy, err := parsePlanChanges(tc.output)
+		if (err != nil) != tc.expectedError {
+			t.Errorf("test case %s: expected error: %v, got: %v", tc.test, tc.expectedError, err)
+			continue
+		}
+		if add != tc.add || change != tc.change || destroy != tc.destroy {
+			t.Errorf("test case %s: expected %d/%d/%d, got %d/%d/%d", tc.test, tc.add, tc.change, tc.destroy, add, change, destroy)
+		}
+	}
+}
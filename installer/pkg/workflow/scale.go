@@ -0,0 +1,169 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+const scaleWorkflow = "scale"
+
+// scalePlan lists the node count changes of every role.
+type scalePlan struct {
+	roles []roleScale
+}
+
+// roleScale is the node count change of a role, or of a worker pool, as
+// every worker pool is a group of nodes of its own.
+type roleScale struct {
+	role  string
+	pools []string
+	// before is the number of nodes recorded in the state of the role's step.
+	before int
+	after  int
+}
+
+// changed returns whether the node count of the given role, or of one of
+// its pools, changes.
+func (p *scalePlan) changed(role string) bool {
+	for _, r := range p.roles {
+		if r.role == role && r.before != r.after {
+			return true
+		}
+	}
+	return false
+}
+
+// roleScales compares the node counts configured for the given pools of a
+// role to the ones recorded in the state of the role's step. The worker
+// pools are compared one by one, so that moving nodes between them is a
+// change; the pools of the other roles share their nodes.
+func roleScales(c *config.Cluster, role string, pools []string, state *tfState) []roleScale {
+	if role != "worker" {
+		return []roleScale{{role: role, pools: pools, before: state.nodeCount(), after: c.NodeCount(pools)}}
+	}
+
+	recorded := state.poolNodeCounts()
+	var scales []roleScale
+	for _, pool := range pools {
+		scales = append(scales, roleScale{role: role, pools: []string{pool}, before: recorded[pool], after: c.NodeCount([]string{pool})})
+		delete(recorded, pool)
+	}
+	// The nodes of the pools no longer configured are removed.
+	var removed []string
+	for pool, n := range recorded {
+		if n > 0 {
+			removed = append(removed, pool)
+		}
+	}
+	sort.Strings(removed)
+	for _, pool := range removed {
+		scales = append(scales, roleScale{role: role, pools: []string{pool}, before: recorded[pool]})
+	}
+	return scales
+}
+
+// ScaleWorkflow creates new instances of the 'scale' workflow,
+// responsible for changing the number of master and worker nodes of an
+// installed cluster to the counts of their node pools in the config.
+// Only the steps of the roles whose count changes are applied. Scaling is
+// not resumed but run again, so it keeps the journal of the install.
+func ScaleWorkflow(clusterDir string) Workflow {
+	return Workflow{
+		name:        scaleWorkflow,
+		unjournaled: true,
+		metadata:    metadata{clusterDir: clusterDir},
+		steps: []Step{
+			refreshConfigStep,
+			planScaleStep,
+			scaleMastersStep,
+			scaleWorkersStep,
+		},
+	}
+}
+
+// planScaleStep compares the node counts configured for every role, and
+// every worker pool, to the ones recorded in the state of the role's step,
+// and refuses the changes the cluster cannot safely go through.
+func planScaleStep(m *metadata) error {
+	p := &scalePlan{}
+	var problems []string
+	for _, role := range []struct {
+		name  string
+		pools []string
+		step  string
+	}{
+		{name: "etcd", pools: m.cluster.Etcd.NodePools, step: etcdStep},
+		{name: "master", pools: m.cluster.Master.NodePools, step: mastersStep},
+		{name: "worker", pools: m.cluster.Worker.NodePools, step: joinWorkersStep},
+	} {
+		if !hasStateFile(m.clusterDir, role.step) {
+			return fmt.Errorf("the %s step was never applied; install the cluster before scaling it", role.step)
+		}
+		state, err := readStateFile(m.clusterDir, role.step)
+		if err != nil {
+			return err
+		}
+		for _, r := range roleScales(&m.cluster, role.name, role.pools, state) {
+			p.roles = append(p.roles, r)
+
+			switch {
+			case r.before == r.after:
+			case r.role == "etcd":
+				// Changing the etcd members requires reconfiguring the etcd
+				// cluster, which the etcd step does not do.
+				problems = append(problems, fmt.Sprintf("etcd cannot be scaled (from %d to %d nodes)", r.before, r.after))
+			case r.role == "master" && r.after < 1:
+				problems = append(problems, fmt.Sprintf("the cluster needs at least one master node, %d configured", r.after))
+			}
+		}
+	}
+
+	if err := printScalePlan(m.output(), p); err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("refusing to scale the cluster: %s", strings.Join(problems, "; "))
+	}
+	if !p.changed("master") && !p.changed("worker") {
+		log.Info("The node counts match the config, nothing to scale")
+	}
+	m.scale = p
+	return nil
+}
+
+func printScalePlan(out io.Writer, p *scalePlan) error {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ROLE\tNODE POOLS\tBEFORE\tAFTER")
+	for _, r := range p.roles {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", r.role, strings.Join(r.pools, ","), r.before, r.after)
+	}
+	return w.Flush()
+}
+
+func scaleMastersStep(m *metadata) error {
+	if m.scale == nil {
+		return errors.New("the scale plan is missing")
+	}
+	if !m.scale.changed("master") {
+		return nil
+	}
+	return installJoinMastersStep(m)
+}
+
+func scaleWorkersStep(m *metadata) error {
+	if m.scale == nil {
+		return errors.New("the scale plan is missing")
+	}
+	if !m.scale.changed("worker") {
+		return nil
+	}
+	return installJoinWorkersStep(m)
+}
@@ -0,0 +1,164 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+func TestScaleWorkflow(t *testing.T) {
+	// The aws.basic fixture configures 3 etcd, 2 master and 3 worker nodes.
+	testCases := []struct {
+		test     string
+		etcd     int
+		masters  string
+		workers  string
+		expected []string
+		err      bool
+	}{
+		{
+			test:     "scale workers",
+			etcd:     3,
+			masters:  "2",
+			workers:  "1",
+			expected: []string{"apply joining_workers"},
+		},
+		{
+			test:     "scale masters",
+			etcd:     3,
+			masters:  "3",
+			workers:  "3",
+			expected: []string{"apply masters tectonic_bootstrap=false"},
+		},
+		{
+			test:    "nothing to scale",
+			etcd:    3,
+			masters: "2",
+			workers: "3",
+		},
+		{
+			test:    "scale etcd",
+			etcd:    5,
+			masters: "2",
+			workers: "1",
+			err:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		clusterDir, baseDir, cleanup := initTestClusterDir(t)
+		defer cleanup()
+		etcd := make(map[string]tfStateResource)
+		for i := 0; i < tc.etcd; i++ {
+			etcd[fmt.Sprintf("aws_instance.etcd_node.%d", i)] = testResource("aws_instance", fmt.Sprintf("i-%d", i), nil)
+		}
+		writeTestState(t, clusterDir, etcdStep, etcd)
+		writeTestState(t, clusterDir, mastersStep, map[string]tfStateResource{
+			"aws_autoscaling_group.masters": testResource("aws_autoscaling_group", "masters", map[string]string{"desired_capacity": tc.masters}),
+		})
+		writeTestState(t, clusterDir, joinWorkersStep, map[string]tfStateResource{
+			"aws_autoscaling_group.workers": testResource("aws_autoscaling_group", "workers", map[string]string{"desired_capacity": tc.workers}),
+		})
+
+		ex := &fakeExecutor{}
+		w := ScaleWorkflow(clusterDir)
+		w.metadata.baseDir = baseDir
+		w.metadata.executor = ex
+		err := w.Execute(context.Background())
+		if (err != nil) != tc.err {
+			t.Errorf("test case %s: expected error %t, got %v", tc.test, tc.err, err)
+		}
+
+		if got := ex.commands("apply"); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("test case %s: expected applies %v, got %v", tc.test, tc.expected, got)
+		}
+		if _, err := os.Stat(filepath.Join(clusterDir, journalFileName)); !os.IsNotExist(err) {
+			t.Errorf("test case %s: expected the scale workflow not to be journaled, got %v", tc.test, err)
+		}
+	}
+}
+
+// workerGroup returns the autoscaling group of the given worker pool.
+func workerGroup(pool, capacity string) tfStateResource {
+	return testResource("aws_autoscaling_group", "workers-"+pool, map[string]string{
+		"desired_capacity": capacity,
+		"tags.#":           "2",
+		"tags.0.key":       "Name",
+		"tags.0.value":     "workers-" + pool,
+		"tags.1.key":       "tectonicNodePool",
+		"tags.1.value":     pool,
+	})
+}
+
+func TestScaleWorkflowBetweenPools(t *testing.T) {
+	clusterDir, baseDir, cleanup := initTestClusterDir(t)
+	defer cleanup()
+
+	// A node moves from the gpu pool to the worker pool, keeping 3 workers.
+	configFilePath := filepath.Join(clusterDir, configFileName)
+	cluster, err := config.ParseConfigFile(configFilePath)
+	if err != nil {
+		t.Fatalf("failed to read cluster config: %v", err)
+	}
+	cluster.NodePools.NodePool("worker").Count = 2
+	cluster.NodePools = append(cluster.NodePools, config.NodePool{Name: "gpu", Count: 1})
+	cluster.Worker.NodePools = []string{"worker", "gpu"}
+	data, err := yaml.Marshal(cluster)
+	if err != nil {
+		t.Fatalf("failed to marshal cluster config: %v", err)
+	}
+	if err := ioutil.WriteFile(configFilePath, data, 0644); err != nil {
+		t.Fatalf("failed to write cluster config: %v", err)
+	}
+
+	etcd := make(map[string]tfStateResource)
+	for i := 0; i < 3; i++ {
+		etcd[fmt.Sprintf("aws_instance.etcd_node.%d", i)] = testResource("aws_instance", fmt.Sprintf("i-%d", i), nil)
+	}
+	writeTestState(t, clusterDir, etcdStep, etcd)
+	writeTestState(t, clusterDir, mastersStep, map[string]tfStateResource{
+		"aws_autoscaling_group.masters": testResource("aws_autoscaling_group", "masters", map[string]string{"desired_capacity": "2"}),
+	})
+	writeTestState(t, clusterDir, joinWorkersStep, map[string]tfStateResource{
+		"aws_autoscaling_group.workers.0": workerGroup("worker", "1"),
+		"aws_autoscaling_group.workers.1": workerGroup("gpu", "2"),
+	})
+
+	m := &metadata{clusterDir: clusterDir}
+	if err := loadClusterConfigStep(m); err != nil {
+		t.Fatalf("failed to load the cluster config: %v", err)
+	}
+	s, err := readClusterStatus(m)
+	if err != nil {
+		t.Fatalf("failed to read the cluster status: %v", err)
+	}
+	var pools []string
+	for _, n := range s.Nodes {
+		if n.Role == "worker" && n.Recorded != nil {
+			pools = append(pools, fmt.Sprintf("%s %d/%d", strings.Join(n.NodePools, ","), n.Configured, *n.Recorded))
+		}
+	}
+	if expected := []string{"worker 2/1", "gpu 1/2"}; !reflect.DeepEqual(pools, expected) {
+		t.Errorf("expected the worker pools status %v, got %v", expected, pools)
+	}
+
+	ex := &fakeExecutor{}
+	w := ScaleWorkflow(clusterDir)
+	w.metadata.baseDir = baseDir
+	w.metadata.executor = ex
+	if err := w.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to execute the workflow: %v", err)
+	}
+	if got, expected := ex.commands("apply"), []string{"apply joining_workers"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected applies %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,93 @@
+package workflow
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+// adminPasswordFileName is the file of the cluster directory holding the
+// admin password generated by init. The password is stored in clear text,
+// not hashed, as it is passed to TerraForm on every apply and the assets
+// take the password itself.
+const adminPasswordFileName = "admin-password"
+
+// terraformSecrets resolves the secrets referenced by the cluster config of
+// the metadata into the environment variables passing them to TerraForm.
+// Secrets TerraForm reads from files are written to temporary files when
+// they are referenced by environment variables. The returned function
+// removes those files.
+func terraformSecrets(m *metadata) ([]string, func(), error) {
+	var env, tempFiles []string
+	cleanup := func() {
+		for _, f := range tempFiles {
+			os.Remove(f)
+		}
+	}
+
+	if ref := m.cluster.Admin.PasswordFrom; ref != nil {
+		password, err := ref.Resolve()
+		if err != nil {
+			return nil, cleanup, fmt.Errorf("failed to resolve admin.passwordFrom: %v", err)
+		}
+		env = append(env, "TF_VAR_tectonic_admin_password="+password)
+	}
+
+	for _, s := range []struct {
+		field    string
+		variable string
+		ref      *config.SecretRef
+	}{
+		{field: "pullSecretFrom", variable: "tectonic_pull_secret_path", ref: m.cluster.PullSecretFrom},
+		{field: "licenseFrom", variable: "tectonic_license_path", ref: m.cluster.LicenseFrom},
+	} {
+		if s.ref == nil {
+			continue
+		}
+		path, err := filepath.Abs(s.ref.File)
+		if s.ref.File == "" {
+			path, err = writeSecretFile(s.ref)
+			if path != "" {
+				tempFiles = append(tempFiles, path)
+			}
+		}
+		if err != nil {
+			return nil, cleanup, fmt.Errorf("failed to resolve %s: %v", s.field, err)
+		}
+		env = append(env, fmt.Sprintf("TF_VAR_%s=%s", s.variable, path))
+	}
+	return env, cleanup, nil
+}
+
+// writeSecretFile writes the given secret to a temporary file only readable
+// by the current user, and returns its path.
+func writeSecretFile(ref *config.SecretRef) (string, error) {
+	secret, err := ref.Resolve()
+	if err != nil {
+		return "", err
+	}
+	f, err := ioutil.TempFile("", "tectonic-secret")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(secret); err != nil {
+		return f.Name(), err
+	}
+	return f.Name(), nil
+}
+
+// writeAdminPassword writes the generated admin password in clear text at
+// the given path, only readable by the current user.
+func writeAdminPassword(path, password string) error {
+	if err := ioutil.WriteFile(path, []byte(password+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to write the admin password at %q: %v", path, err)
+	}
+	log.Infof("Generated an admin password, stored in clear text in %s", path)
+	return nil
+}
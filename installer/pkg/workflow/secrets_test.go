@@ -0,0 +1,139 @@
+package workflow
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+func TestTerraformSecrets(t *testing.T) {
+	os.Setenv("TEST_ADMIN_PASSWORD", "secret")
+	defer os.Unsetenv("TEST_ADMIN_PASSWORD")
+	os.Setenv("TEST_PULL_SECRET", "{}")
+	defer os.Unsetenv("TEST_PULL_SECRET")
+
+	m := &metadata{}
+	m.cluster.Admin.PasswordFrom = &config.SecretRef{Env: "TEST_ADMIN_PASSWORD"}
+	m.cluster.PullSecretFrom = &config.SecretRef{Env: "TEST_PULL_SECRET"}
+	m.cluster.LicenseFrom = &config.SecretRef{File: "/etc/tectonic/license.txt"}
+
+	env, cleanup, err := terraformSecrets(m)
+	if err != nil {
+		t.Fatalf("failed to resolve secrets: %v", err)
+	}
+
+	vars := make(map[string]string)
+	for _, e := range env {
+		kv := strings.SplitN(e, "=", 2)
+		vars[kv[0]] = kv[1]
+	}
+	if password := vars["TF_VAR_tectonic_admin_password"]; password != "secret" {
+		t.Errorf("expected the admin password to be %q, got %q", "secret", password)
+	}
+	if license := vars["TF_VAR_tectonic_license_path"]; license != "/etc/tectonic/license.txt" {
+		t.Errorf("expected the license path to be %q, got %q", "/etc/tectonic/license.txt", license)
+	}
+	pullSecretPath := vars["TF_VAR_tectonic_pull_secret_path"]
+	data, err := ioutil.ReadFile(pullSecretPath)
+	if err != nil {
+		t.Fatalf("failed to read the pull secret file: %v", err)
+	}
+	if string(data) != "{}" {
+		t.Errorf("expected the pull secret file to hold %q, got %q", "{}", data)
+	}
+
+	cleanup()
+	if _, err := os.Stat(pullSecretPath); !os.IsNotExist(err) {
+		t.Errorf("expected the pull secret file to be removed, got %v", err)
+	}
+
+	m.cluster.Admin.PasswordFrom = &config.SecretRef{Env: "TEST_ADMIN_PASSWORD_UNSET"}
+	if _, cleanup, err := terraformSecrets(m); err == nil {
+		t.Error("expected an unset admin password to fail")
+	} else {
+		cleanup()
+	}
+}
+
+func TestTerraformSecretsRelativeFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	ps, lic, err := generatePullSecretAndLicense("secrets", time.Now().AddDate(1, 0, 0))
+	if err != nil {
+		t.Fatalf("failed to generate pull secret and license: %v", err)
+	}
+	defer os.Remove(ps.Name())
+	defer os.Remove(lic.Name())
+
+	// The config references its secrets relatively to its own directory.
+	configDir := filepath.Join(dir, "config")
+	workDir := filepath.Join(dir, "work")
+	for _, d := range []string{configDir, workDir} {
+		if err := os.Mkdir(d, 0755); err != nil {
+			t.Fatalf("failed to create %s: %v", d, err)
+		}
+	}
+	for from, to := range map[string]string{ps.Name(): "pull-secret.json", lic.Name(): "license.txt"} {
+		if err := copyFile(from, filepath.Join(configDir, to)); err != nil {
+			t.Fatalf("failed to copy %s: %v", to, err)
+		}
+	}
+	fixture, err := ioutil.ReadFile("./fixtures/aws.basic.yaml")
+	if err != nil {
+		t.Fatalf("failed to read the fixture: %v", err)
+	}
+	configFilePath := filepath.Join(configDir, "config.yaml")
+	refs := "pullSecretFrom:\n  file: pull-secret.json\nlicenseFrom:\n  file: license.txt\n"
+	if err := ioutil.WriteFile(configFilePath, append(fixture, refs...), 0644); err != nil {
+		t.Fatalf("failed to write the config: %v", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get current directory: %v", err)
+	}
+	defer os.Chdir(wd)
+
+	// The cluster is initialized from another directory, and installed from
+	// yet another one.
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	m := &metadata{configFilePath: configFilePath}
+	if err := prepareWorspaceStep(m); err != nil {
+		t.Fatalf("failed to prepare the workspace: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	if err := loadClusterConfigStep(m); err != nil {
+		t.Fatalf("failed to load the cluster config: %v", err)
+	}
+
+	env, cleanup, err := terraformSecrets(m)
+	if err != nil {
+		t.Fatalf("failed to resolve secrets: %v", err)
+	}
+	defer cleanup()
+	for variable, file := range map[string]string{
+		"TF_VAR_tectonic_pull_secret_path=": "pull-secret.json",
+		"TF_VAR_tectonic_license_path=":     "license.txt",
+	} {
+		expected := variable + filepath.Join(configDir, file)
+		found := false
+		for _, e := range env {
+			found = found || e == expected
+		}
+		if !found {
+			t.Errorf("expected %q in the environment, got %q", expected, env)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
+
+const statusWorkflow = "status"
+
+// clusterStatus is the lifecycle state of a cluster, as recorded in its
+// cluster directory.
+type clusterStatus struct {
+	Name         string          `json:"name"`
+	ClusterID    string          `json:"clusterId"`
+	Platform     config.Platform `json:"platform"`
+	Bootstrapped bool            `json:"bootstrapped"`
+	LastWorkflow *workflowReport `json:"lastWorkflow,omitempty"`
+	Steps        []stepReport    `json:"steps"`
+	Nodes        []nodesReport   `json:"nodes"`
+}
+
+// workflowReport describes the last journaled workflow run in the cluster directory.
+type workflowReport struct {
+	Name string `json:"name"`
+	// IncompleteStep is the first step that did not finish, if any.
+	IncompleteStep string     `json:"incompleteStep,omitempty"`
+	Status         stepStatus `json:"status"`
+}
+
+// stepReport describes the state of a terraform step.
+type stepReport struct {
+	Step      string `json:"step"`
+	Applied   bool   `json:"applied"`
+	Resources int    `json:"resources"`
+}
+
+// nodesReport compares the configured and recorded number of nodes of a
+// role, or of a worker pool once the workers are applied.
+type nodesReport struct {
+	Role       string   `json:"role"`
+	NodePools  []string `json:"nodePools"`
+	Configured int      `json:"configured"`
+	// Recorded is the number of nodes recorded in the state of the role's
+	// step, or nil if the step was not applied.
+	Recorded *int `json:"recorded,omitempty"`
+}
+
+// StatusWorkflow creates new instances of the 'status' workflow,
+// responsible for reporting the lifecycle state of a cluster from its
+// cluster directory. It does not change anything, so it neither locks
+// nor journals the cluster directory.
+func StatusWorkflow(clusterDir string, jsonOutput bool) Workflow {
+	printStep := printStatusStep
+	if jsonOutput {
+		printStep = printStatusJSONStep
+	}
+	return Workflow{
+		name:     statusWorkflow,
+		readOnly: true,
+		metadata: metadata{clusterDir: clusterDir},
+		steps: []Step{
+			loadClusterConfigStep,
+			printStep,
+		},
+	}
+}
+
+// readClusterStatus builds the status of the cluster from its cluster directory.
+func readClusterStatus(m *metadata) (*clusterStatus, error) {
+	s := &clusterStatus{
+		Name:         m.cluster.Name,
+		ClusterID:    m.cluster.Internal.ClusterID,
+		Platform:     m.cluster.Platform,
+		Bootstrapped: clusterIsBootstrapped(m.clusterDir),
+	}
+
+	if j, err := readJournal(m.clusterDir); err == nil {
+		s.LastWorkflow = &workflowReport{Name: j.Workflow, Status: stepFinished}
+		if i := j.firstIncomplete(); i >= 0 {
+			s.LastWorkflow.IncompleteStep = j.Steps[i].Step
+			s.LastWorkflow.Status = j.Steps[i].Status
+		}
+	}
+
+	applied := make(map[string]*tfState)
+	for _, step := range []string{tlsStep, assetsStep, topologyStep, tncDNSStep, etcdStep, mastersStep, joinWorkersStep} {
+		r := stepReport{Step: step}
+		if hasStateFile(m.clusterDir, step) {
+			state, err := readStateFile(m.clusterDir, step)
+			if err != nil {
+				return nil, err
+			}
+			r.Resources = len(state.resources())
+			r.Applied = r.Resources > 0
+			if r.Applied {
+				applied[step] = state
+			}
+		}
+		s.Steps = append(s.Steps, r)
+	}
+
+	for _, role := range []struct {
+		name  string
+		pools []string
+		step  string
+	}{
+		{name: "etcd", pools: m.cluster.Etcd.NodePools, step: etcdStep},
+		{name: "master", pools: m.cluster.Master.NodePools, step: mastersStep},
+		{name: "worker", pools: m.cluster.Worker.NodePools, step: joinWorkersStep},
+	} {
+		state, ok := applied[role.step]
+		if !ok {
+			s.Nodes = append(s.Nodes, nodesReport{
+				Role:       role.name,
+				NodePools:  role.pools,
+				Configured: m.cluster.NodeCount(role.pools),
+			})
+			continue
+		}
+		for _, r := range roleScales(&m.cluster, role.name, role.pools, state) {
+			recorded := r.before
+			s.Nodes = append(s.Nodes, nodesReport{
+				Role:       r.role,
+				NodePools:  r.pools,
+				Configured: r.after,
+				Recorded:   &recorded,
+			})
+		}
+	}
+
+	return s, nil
+}
+
+func printStatusJSONStep(m *metadata) error {
+	s, err := readClusterStatus(m)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(m.output(), string(data))
+	return err
+}
+
+func printStatusStep(m *metadata) error {
+	s, err := readClusterStatus(m)
+	if err != nil {
+		return err
+	}
+
+	out := m.output()
+	fmt.Fprintf(out, "Cluster:      %s (%s)\n", s.Name, s.ClusterID)
+	fmt.Fprintf(out, "Platform:     %s\n", s.Platform)
+	fmt.Fprintf(out, "Bootstrapped: %s\n", yesNo(s.Bootstrapped))
+	if s.LastWorkflow != nil {
+		if s.LastWorkflow.IncompleteStep == "" {
+			fmt.Fprintf(out, "Last run:     %s finished\n", s.LastWorkflow.Name)
+		} else {
+			fmt.Fprintf(out, "Last run:     %s %s at %s\n", s.LastWorkflow.Name, s.LastWorkflow.Status, s.LastWorkflow.IncompleteStep)
+		}
+	}
+	fmt.Fprintln(out)
+
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "STEP\tAPPLIED\tRESOURCES")
+	for _, r := range s.Steps {
+		fmt.Fprintf(w, "%s\t%s\t%d\n", r.Step, yesNo(r.Applied), r.Resources)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(out)
+
+	w = tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ROLE\tNODE POOLS\tCONFIGURED\tRECORDED")
+	for _, r := range s.Nodes {
+		recorded := "-"
+		if r.Recorded != nil {
+			recorded = fmt.Sprintf("%d", *r.Recorded)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", r.Role, strings.Join(r.NodePools, ","), r.Configured, recorded)
+	}
+	return w.Flush()
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
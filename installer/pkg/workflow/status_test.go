@@ -0,0 +1,106 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// writeTestState writes a state file for the given step holding resources
+// of the given types, keyed by their address.
+func writeTestState(t *testing.T, clusterDir, step string, resources map[string]tfStateResource) {
+	state := tfState{
+		Version: 3,
+		Modules: []tfStateModule{{Path: []string{"root"}, Resources: resources}},
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("failed to marshal %s state: %v", step, err)
+	}
+	if err := writeFile(stateFilePath(clusterDir, step), string(data)); err != nil {
+		t.Fatalf("failed to write %s state: %v", step, err)
+	}
+}
+
+func testResource(resourceType, id string, attributes map[string]string) tfStateResource {
+	r := tfStateResource{Type: resourceType}
+	r.Primary.ID = id
+	r.Primary.Attributes = attributes
+	return r
+}
+
+func TestReadClusterStatus(t *testing.T) {
+	clusterDir, _, cleanup := initTestClusterDir(t)
+	defer cleanup()
+
+	writeTestState(t, clusterDir, topologyStep, map[string]tfStateResource{
+		"aws_vpc.cluster_vpc":             testResource("aws_vpc", "vpc-1", nil),
+		"data.aws_availability_zones.azs": testResource("aws_availability_zones", "azs", nil),
+	})
+	writeTestState(t, clusterDir, tncDNSStep, map[string]tfStateResource{
+		"aws_route53_record.tnc": testResource("aws_route53_record", "tnc", nil),
+	})
+	writeTestState(t, clusterDir, mastersStep, map[string]tfStateResource{
+		"aws_autoscaling_group.masters": testResource("aws_autoscaling_group", "masters", map[string]string{"desired_capacity": "2"}),
+		"aws_launch_configuration.conf": testResource("aws_launch_configuration", "conf", nil),
+	})
+	// A destroyed step leaves an empty state behind.
+	writeTestState(t, clusterDir, etcdStep, nil)
+
+	m := &metadata{clusterDir: clusterDir}
+	if err := loadClusterConfigStep(m); err != nil {
+		t.Fatalf("failed to load cluster config: %v", err)
+	}
+	s, err := readClusterStatus(m)
+	if err != nil {
+		t.Fatalf("failed to read cluster status: %v", err)
+	}
+
+	if !s.Bootstrapped {
+		t.Error("expected the cluster to be bootstrapped")
+	}
+	expectedResources := map[string]int{topologyStep: 1, tncDNSStep: 1, mastersStep: 2}
+	for _, r := range s.Steps {
+		if r.Resources != expectedResources[r.Step] || r.Applied != (expectedResources[r.Step] > 0) {
+			t.Errorf("step %s: expected %d resources, got %d (applied: %v)", r.Step, expectedResources[r.Step], r.Resources, r.Applied)
+		}
+	}
+
+	expectedNodes := map[string]struct {
+		configured int
+		recorded   int
+	}{
+		"etcd":   {configured: 3, recorded: -1},
+		"master": {configured: 2, recorded: 2},
+		"worker": {configured: 3, recorded: -1},
+	}
+	for _, r := range s.Nodes {
+		exp := expectedNodes[r.Role]
+		recorded := -1
+		if r.Recorded != nil {
+			recorded = *r.Recorded
+		}
+		if r.Configured != exp.configured || recorded != exp.recorded {
+			t.Errorf("role %s: expected %d configured and %d recorded nodes, got %d and %d", r.Role, exp.configured, exp.recorded, r.Configured, recorded)
+		}
+	}
+}
+
+func TestStatusWorkflowOutput(t *testing.T) {
+	clusterDir, _, cleanup := initTestClusterDir(t)
+	defer cleanup()
+
+	for _, format := range []string{"text", "json"} {
+		var out bytes.Buffer
+		w := StatusWorkflow(clusterDir, format == "json")
+		w.metadata.out = &out
+		if err := w.Execute(context.Background()); err != nil {
+			t.Fatalf("test case %s: failed to execute workflow: %v", format, err)
+		}
+		if !strings.Contains(out.String(), "aws-basic") {
+			t.Errorf("test case %s: expected the status to be written to the workflow output, got %q", format, out.String())
+		}
+	}
+}
@@ -1,26 +1,132 @@
 package workflow
 
 import (
+	"bufio"
+	"bytes"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
 )
 
-func terraformExec(clusterDir string, args ...string) error {
-	// Create an executor
-	ex, err := newExecutor()
+// ErrTerraform is returned when TerraForm fails to run for a step.
+type ErrTerraform struct {
+	// Step is the name of the workflow step TerraForm failed for.
+	Step string
+	// StateFile is the path of the state file TerraForm ran against.
+	StateFile string
+	// LogFile is the path of the file holding the full TerraForm output.
+	LogFile string
+	// Summary is the error reported by TerraForm, extracted from its output.
+	Summary string
+	// Err is the error TerraForm exited with.
+	Err error
+}
+
+// ErrTerraform implements the error interface.
+func (e *ErrTerraform) Error() string {
+	msg := fmt.Sprintf("Failed to run Terraform for step %s: %v", e.Step, e.Err)
+	if e.Summary != "" {
+		msg = fmt.Sprintf("%s\n%s", msg, e.Summary)
+	}
+	return fmt.Sprintf("%s\nstate file: %s, full output: %s", msg, e.StateFile, e.LogFile)
+}
+
+// terraformExec runs TerraForm against the state of the given step. The
+// output of TerraForm is appended to the log file of the running workflow
+// step and, if out is not nil, copied to out as well. The workflow steps
+// sharing a state, such as installBootstrapStep and installJoinMastersStep,
+// each have a log file of their own.
+func terraformExec(m *metadata, state string, out io.Writer, args ...string) error {
+	ex, err := m.terraform()
 	if err != nil {
-		return fmt.Errorf("Could not create Terraform executor: %s", err)
+		return err
 	}
 
-	err = ex.execute(clusterDir, args...)
+	step := m.step
+	if step == "" {
+		step = state
+	}
+	logsDir := m.logsDir
+	if logsDir == "" {
+		logsDir = filepath.Join(m.clusterDir, logsPath)
+	}
+	logFilePath := filepath.Join(logsDir, fmt.Sprintf("%s.log", step))
+	logFile, err := openStepLog(logFilePath, args)
 	if err != nil {
-		return fmt.Errorf("Failed to run Terraform: %s", err)
+		return err
 	}
+	defer logFile.Close()
+
+	var output bytes.Buffer
+	writers := []io.Writer{logFile, &output}
+	if out != nil {
+		writers = append(writers, out)
+	}
+
+	env, cleanup, err := terraformSecrets(m)
+	defer cleanup()
+	if err != nil {
+		return err
+	}
+
+	err = ex.execute(m.ctx, m.clusterDir, env, io.MultiWriter(writers...), args...)
+	if err != nil {
+		return &ErrTerraform{
+			Step:      step,
+			StateFile: stateFilePath(m.clusterDir, state),
+			LogFile:   logFilePath,
+			Summary:   terraformErrorSummary(output.String()),
+			Err:       err,
+		}
+	}
+	m.emitTerraformResources(step, output.String())
 	return nil
 }
 
-func tfApply(clusterDir string, state string, templateDir string, extraArgs ...string) error {
+// openStepLog opens the log file of a step for appending the output of the
+// given TerraForm command.
+func openStepLog(path string, args []string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModeDir|0755); err != nil {
+		return nil, fmt.Errorf("failed to create logs directory at %s", filepath.Dir(path))
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file at %s: %v", path, err)
+	}
+	if _, err := fmt.Fprintf(f, "==> %s terraform %s\n", time.Now().UTC().Format(time.RFC3339), strings.Join(args, " ")); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// terraformErrorSummary extracts the error block from the output of TerraForm,
+// that is everything from the first line starting with "Error" up to the
+// advice TerraForm prints after it.
+func terraformErrorSummary(output string) string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(ansiEscapeRegexp.ReplaceAllString(output, "")))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(lines) == 0 && !strings.HasPrefix(line, "Error") {
+			continue
+		}
+		if strings.HasPrefix(line, "Terraform does not automatically rollback") {
+			break
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func tfApply(m *metadata, state string, templateDir string, extraArgs ...string) error {
 	defaultArgs := []string{
 		"apply",
 		"-auto-approve",
@@ -28,10 +134,13 @@ func tfApply(clusterDir string, state string, templateDir string, extraArgs ...s
 	}
 	extraArgs = append(extraArgs, templateDir)
 	args := append(defaultArgs, extraArgs...)
-	return terraformExec(clusterDir, args...)
+	if err := terraformExec(m, state, nil, args...); err != nil {
+		return err
+	}
+	return writeLastAppliedConfig(m)
 }
 
-func tfDestroy(clusterDir, state, templateDir string, extraArgs ...string) error {
+func tfDestroy(m *metadata, state, templateDir string, extraArgs ...string) error {
 	defaultArgs := []string{
 		"destroy",
 		"-force",
@@ -39,15 +148,47 @@ func tfDestroy(clusterDir, state, templateDir string, extraArgs ...string) error
 	}
 	extraArgs = append(extraArgs, templateDir)
 	args := append(defaultArgs, extraArgs...)
-	return terraformExec(clusterDir, args...)
+	return terraformExec(m, state, nil, args...)
+}
+
+// tfPlan runs a detailed plan of the given step and returns its output.
+// The returned boolean reports whether the plan contains any changes.
+func tfPlan(m *metadata, state, templateDir string, extraArgs ...string) (bool, string, error) {
+	defaultArgs := []string{
+		"plan",
+		"-detailed-exitcode",
+		fmt.Sprintf("-state=%s.tfstate", state),
+	}
+	extraArgs = append(extraArgs, templateDir)
+	args := append(defaultArgs, extraArgs...)
+
+	var out bytes.Buffer
+	err := terraformExec(m, state, &out, args...)
+	if err == nil {
+		return false, out.String(), nil
+	}
+	// With -detailed-exitcode, terraform exits with 2 on a successful plan
+	// that contains changes.
+	if tfErr, ok := err.(*ErrTerraform); ok {
+		if exitErr, ok := tfErr.Err.(*exec.ExitError); ok {
+			if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.ExitStatus() == 2 {
+				return true, out.String(), nil
+			}
+		}
+	}
+	return false, out.String(), err
+}
+
+func tfInit(m *metadata, step, templateDir string) error {
+	return terraformExec(m, step, nil, "init", templateDir)
 }
 
-func tfInit(clusterDir, templateDir string) error {
-	return terraformExec(clusterDir, "init", templateDir)
+// stateFilePath returns the path of the state file of the given step.
+func stateFilePath(stateDir string, stateName string) string {
+	return filepath.Join(stateDir, fmt.Sprintf("%s.tfstate", stateName))
 }
 
 func hasStateFile(stateDir string, stateName string) bool {
-	stepStateFile := filepath.Join(stateDir, fmt.Sprintf("%s.tfstate", stateName))
-	_, err := os.Stat(stepStateFile)
+	_, err := os.Stat(stateFilePath(stateDir, stateName))
 	return !os.IsNotExist(err)
 }
@@ -0,0 +1,143 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const terraformFailureOutput = `aws_instance.master: Creating...
+Error: Error applying plan:
+
+1 error(s) occurred:
+
+* aws_instance.master: Error launching source instance: InvalidKeyPair.NotFound
+
+Terraform does not automatically rollback in the face of errors.
+Instead, your Terraform state file has been partially updated with
+any resources that successfully completed.
+`
+
+func TestTerraformErrorSummary(t *testing.T) {
+	testCases := []struct {
+		test     string
+		output   string
+		expected string
+	}{
+		{
+			test:     "apply failure",
+			output:   terraformFailureOutput,
+			expected: "Error: Error applying plan:\n1 error(s) occurred:\n* aws_instance.master: Error launching source instance: InvalidKeyPair.NotFound",
+		},
+		{
+			test:     "colored error",
+			output:   "\x1b[31mError: Error refreshing state: 1 error(s) occurred\x1b[0m\n",
+			expected: "Error: Error refreshing state: 1 error(s) occurred",
+		},
+		{
+			test:     "no error",
+			output:   "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.\n",
+			expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		if got := terraformErrorSummary(tc.output); got != tc.expected {
+			t.Errorf("test case %s: expected: %q, got: %q", tc.test, tc.expected, got)
+		}
+	}
+}
+
+func TestTerraformExecFailure(t *testing.T) {
+	clusterDir, baseDir, cleanup := initTestClusterDir(t)
+	defer cleanup()
+
+	ex := &fakeExecutor{
+		output: func(invocation) string { return terraformFailureOutput },
+		fail:   func(invocation) error { return errors.New("exit status 1") },
+	}
+	m := &metadata{
+		ctx:        context.Background(),
+		clusterDir: clusterDir,
+		baseDir:    baseDir,
+		executor:   ex,
+	}
+	err := tfApply(m, mastersStep, filepath.Join(baseDir, stepsBaseDir, mastersStep))
+	tfErr, ok := err.(*ErrTerraform)
+	if !ok {
+		t.Fatalf("expected an ErrTerraform, got: %v", err)
+	}
+
+	if tfErr.Step != mastersStep {
+		t.Errorf("expected step %s, got %s", mastersStep, tfErr.Step)
+	}
+	if expected := stateFilePath(clusterDir, mastersStep); tfErr.StateFile != expected {
+		t.Errorf("expected state file %s, got %s", expected, tfErr.StateFile)
+	}
+	if !strings.Contains(tfErr.Summary, "InvalidKeyPair.NotFound") {
+		t.Errorf("expected the summary to contain the terraform error, got: %q", tfErr.Summary)
+	}
+
+	expectedLogFile := filepath.Join(clusterDir, logsPath, "masters.log")
+	if tfErr.LogFile != expectedLogFile {
+		t.Errorf("expected log file %s, got %s", expectedLogFile, tfErr.LogFile)
+	}
+	log, err := ioutil.ReadFile(expectedLogFile)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if !strings.Contains(string(log), terraformFailureOutput) {
+		t.Errorf("expected the log file to contain the terraform output, got: %q", log)
+	}
+}
+
+func TestTerraformExecFailureStep(t *testing.T) {
+	clusterDir, baseDir, cleanup := initTestClusterDir(t)
+	defer cleanup()
+
+	// The bootstrap and join masters steps share the masters state.
+	ex := &fakeExecutor{
+		output: func(i invocation) string {
+			if i.command() != "apply" {
+				return ""
+			}
+			return fmt.Sprintf("applied %s\n", i.vars["tectonic_bootstrap"])
+		},
+		fail: func(i invocation) error {
+			if i.command() == "apply" && i.state() == mastersStep && i.vars["tectonic_bootstrap"] == "false" {
+				return errors.New("exit status 1")
+			}
+			return nil
+		},
+	}
+	w := InstallFullWorkflow(clusterDir)
+	w.metadata.baseDir = baseDir
+	w.metadata.executor = ex
+	err := w.Execute(context.Background())
+	tfErr, ok := err.(*ErrTerraform)
+	if !ok {
+		t.Fatalf("expected an ErrTerraform, got: %v", err)
+	}
+
+	if tfErr.Step != "installJoinMastersStep" {
+		t.Errorf("expected step installJoinMastersStep, got %s", tfErr.Step)
+	}
+	if expected := stateFilePath(clusterDir, mastersStep); tfErr.StateFile != expected {
+		t.Errorf("expected state file %s, got %s", expected, tfErr.StateFile)
+	}
+	for step, output := range map[string]string{"installBootstrapStep": "applied true", "installJoinMastersStep": "applied false"} {
+		path := filepath.Join(clusterDir, logsPath, step+".log")
+		log, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Errorf("failed to read the log file of %s: %v", step, err)
+			continue
+		}
+		if !strings.Contains(string(log), output) || strings.Count(string(log), "applied") != 1 {
+			t.Errorf("expected the log file of %s to only contain %q, got: %q", step, output, log)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tfState is the subset of a TerraForm state file read by the installer.
+type tfState struct {
+	Version int             `json:"version"`
+	Modules []tfStateModule `json:"modules"`
+}
+
+// tfStateModule is a module of a TerraForm state file.
+type tfStateModule struct {
+	Path      []string                   `json:"path"`
+	Resources map[string]tfStateResource `json:"resources"`
+}
+
+// tfStateResource is a resource of a TerraForm state file.
+type tfStateResource struct {
+	Type    string `json:"type"`
+	Primary struct {
+		ID         string            `json:"id"`
+		Attributes map[string]string `json:"attributes"`
+	} `json:"primary"`
+}
+
+// stateResource is a managed resource recorded in a state file.
+type stateResource struct {
+	// Address is the address of the resource, e.g. "module.masters.aws_instance.master.0".
+	Address    string
+	Type       string
+	ID         string
+	Attributes map[string]string
+}
+
+// readStateFile reads the state file of the given step.
+func readStateFile(stateDir, stateName string) (*tfState, error) {
+	return readState(stateFilePath(stateDir, stateName))
+}
+
+// readState reads the state file at the given path.
+func readState(path string) (*tfState, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &tfState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("%s is not a valid state file: %v", path, err)
+	}
+	return state, nil
+}
+
+// resources returns the managed resources of the state, sorted by address.
+// Data sources are not included.
+func (s *tfState) resources() []stateResource {
+	var resources []stateResource
+	for _, module := range s.Modules {
+		prefix := ""
+		for _, p := range module.Path {
+			if p != "root" {
+				prefix += fmt.Sprintf("module.%s.", p)
+			}
+		}
+		for key, r := range module.Resources {
+			if strings.HasPrefix(key, "data.") {
+				continue
+			}
+			resources = append(resources, stateResource{
+				Address:    prefix + key,
+				Type:       r.Type,
+				ID:         r.Primary.ID,
+				Attributes: r.Primary.Attributes,
+			})
+		}
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].Address < resources[j].Address
+	})
+	return resources
+}
+
+// nodeCount returns the number of nodes recorded in the state. Nodes in
+// autoscaling groups are counted by the desired capacity of their group,
+// other nodes by their instances or domains.
+func (s *tfState) nodeCount() int {
+	var asgs, capacity, instances int
+	for _, r := range s.resources() {
+		switch r.Type {
+		case "aws_autoscaling_group":
+			asgs++
+			n, _ := strconv.Atoi(r.Attributes["desired_capacity"])
+			capacity += n
+		case "aws_instance", "libvirt_domain":
+			instances++
+		}
+	}
+	if asgs > 0 {
+		return capacity
+	}
+	return instances
+}
+
+// poolNodeCounts returns the number of nodes of every worker pool recorded
+// in the state. Autoscaling groups are counted by their desired capacity
+// and attributed to a pool by their tectonicNodePool tag, libvirt domains by
+// their name. The nodes of the clusters installed before worker pools
+// belong to the pool named worker.
+func (s *tfState) poolNodeCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, r := range s.resources() {
+		switch r.Type {
+		case "aws_autoscaling_group":
+			n, _ := strconv.Atoi(r.Attributes["desired_capacity"])
+			counts[asgNodePool(r.Attributes)] += n
+		case "libvirt_domain":
+			counts[libvirtDomainPool(r.Attributes["name"])]++
+		}
+	}
+	return counts
+}
+
+// asgNodePool returns the value of the tectonicNodePool tag of the
+// autoscaling group of the given attributes, or "worker" if it has none.
+func asgNodePool(attributes map[string]string) string {
+	n, _ := strconv.Atoi(attributes["tags.#"])
+	for i := 0; i < n; i++ {
+		if attributes[fmt.Sprintf("tags.%d.key", i)] == "tectonicNodePool" {
+			return attributes[fmt.Sprintf("tags.%d.value", i)]
+		}
+	}
+	return "worker"
+}
+
+// libvirtDomainPool returns the worker pool of the libvirt domain of the
+// given name, which is either worker-<pool>-<index> or worker<index>.
+func libvirtDomainPool(name string) string {
+	if !strings.HasPrefix(name, "worker-") {
+		return "worker"
+	}
+	pool := strings.TrimPrefix(name, "worker-")
+	if i := strings.LastIndex(pool, "-"); i >= 0 {
+		pool = pool[:i]
+	}
+	return pool
+}
+
+// libvirtDomainNames returns the names of the libvirt domains recorded in
+// the state, in the order of their index.
+func (s *tfState) libvirtDomainNames() []string {
+	type domain struct {
+		index int
+		name  string
+	}
+	var domains []domain
+	for _, r := range s.resources() {
+		if r.Type != "libvirt_domain" {
+			continue
+		}
+		// The address of a single domain has no index.
+		index, _ := strconv.Atoi(r.Address[strings.LastIndex(r.Address, ".")+1:])
+		domains = append(domains, domain{index: index, name: r.Attributes["name"]})
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		return domains[i].index < domains[j].index
+	})
+	var names []string
+	for _, d := range domains {
+		names = append(names, d.name)
+	}
+	return names
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -47,16 +48,48 @@ func copyFile(fromFilePath, toFilePath string) error {
 	return err
 }
 
-// returns the directory containing templates for a given step. If platform is
-// specified, it looks for a subdirectory with platform first, falling back if
-// there are no platform-specific templates for that step
-func findStepTemplates(stepName string, platform config.Platform) (string, error) {
-	base, err := baseLocation()
-	if err != nil {
-		return "", fmt.Errorf("error looking up step %s templates: %v", stepName, err)
+// copyDir copies the given directory, with its files, subdirectories and
+// symlinks, to a new directory.
+func copyDir(fromDir, toDir string) error {
+	return filepath.Walk(fromDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(fromDir, path)
+		if err != nil {
+			return err
+		}
+		to := filepath.Join(toDir, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(to, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(target, to)
+		}
+		if err := copyFile(path, to); err != nil {
+			return err
+		}
+		return os.Chmod(to, info.Mode().Perm())
+	})
+}
+
+// returns the directory containing templates for a given step. It looks for a
+// subdirectory with the platform of the cluster first, falling back if there
+// are no platform-specific templates for that step
+func (m *metadata) findStepTemplates(stepName string) (string, error) {
+	base := m.baseDir
+	if base == "" {
+		var err error
+		if base, err = baseLocation(); err != nil {
+			return "", fmt.Errorf("error looking up step %s templates: %v", stepName, err)
+		}
 	}
 	for _, path := range []string{
-		filepath.Join(base, stepsBaseDir, stepName, platformPath(platform)),
+		filepath.Join(base, stepsBaseDir, stepName, platformPath(m.cluster.Platform)),
 		filepath.Join(base, stepsBaseDir, stepName)} {
 
 		stat, err := os.Stat(path)
@@ -158,7 +191,9 @@ func readClusterConfig(configFilePath string, internalFilePath string) (*config.
 	return cfg, nil
 }
 
-func readClusterConfigStep(m *metadata) error {
+// loadClusterConfigStep reads the cluster config from the cluster directory
+// into the metadata, without validating it.
+func loadClusterConfigStep(m *metadata) error {
 	if m.clusterDir == "" {
 		return errors.New("no cluster dir given for reading config")
 	}
@@ -169,16 +204,46 @@ func readClusterConfigStep(m *metadata) error {
 	if err != nil {
 		return err
 	}
-
-	if err := cluster.ValidateAndLog(); err != nil {
+	data, err := ioutil.ReadFile(configFilePath)
+	if err != nil {
 		return err
 	}
 
 	m.cluster = *cluster
+	m.configData = data
 
 	return nil
 }
 
+func readClusterConfigStep(m *metadata) error {
+	if err := loadClusterConfigStep(m); err != nil {
+		return err
+	}
+	return validateClusterConfig(m, &m.cluster)
+}
+
+// validateClusterConfig validates the given cluster config, logging and
+// emitting any errors found. The config of a workflow is validated once,
+// even if steps read it again, so that its warnings are reported once.
+func validateClusterConfig(m *metadata, cluster *config.Cluster) error {
+	if m.validated {
+		return nil
+	}
+	errs, err := cluster.ValidateAndLog()
+	if err == nil {
+		m.validated = true
+		return nil
+	}
+
+	e := Event{Type: EventValidationFailed}
+	for _, err := range errs {
+		e.Errors = append(e.Errors, err.Error())
+	}
+	m.emit(e)
+
+	return err
+}
+
 func writeFile(path, content string) error {
 	f, err := os.Create(path)
 	if err != nil {
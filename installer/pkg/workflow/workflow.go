@@ -1,6 +1,19 @@
 package workflow
 
-import "github.com/coreos/tectonic-installer/installer/pkg/config"
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/coreos/tectonic-installer/installer/pkg/config"
+)
 
 // metadata is the state store of the current workflow execution.
 // It is meant to carry state for one step to another.
@@ -9,9 +22,85 @@ import "github.com/coreos/tectonic-installer/installer/pkg/config"
 // Steps taked their inputs from the metadata object and persist
 // results onto it for later consumption.
 type metadata struct {
+	// ctx is the context of the workflow execution. It is cancelled
+	// when the workflow is interrupted.
+	ctx            context.Context
 	cluster        config.Cluster
 	configFilePath string
+	// configOverlays lists the config files the init workflow merges over
+	// the config file, in order.
+	configOverlays []string
 	clusterDir     string
+	// configData is the content of the cluster config file of clusterDir,
+	// as it was loaded into cluster.
+	configData []byte
+	// logsDir is the directory TerraForm logs the output of the steps to.
+	// It defaults to the logs directory of clusterDir.
+	logsDir string
+	// baseDir is the directory holding the step templates. It defaults to
+	// the location of the installer binary.
+	baseDir string
+	// executor runs TerraForm for the terraform steps. It defaults to an
+	// executor of the TerraForm binary found on disk.
+	executor terraformExecutor
+	// events, when set, receives the events describing the progress of
+	// the workflow.
+	events *eventEmitter
+	// plan, when set, makes terraform steps record a plan into it
+	// instead of applying their changes.
+	plan *planSummary
+	// dryRun, when set, makes destroy steps list the resources of their
+	// state instead of destroying them. It records the states listed.
+	dryRun map[string]bool
+	// scale holds the node count changes applied by the scale workflow.
+	scale *scalePlan
+	// failures lists the steps which still failed after being retried by
+	// a workflow continuing on errors.
+	failures []stepFailure
+	// step is the name of the running step.
+	step string
+	// validated is set once the cluster config was validated.
+	validated bool
+	// out, when set, receives the reports printed by the steps.
+	out io.Writer
+	// cleanups run once the workflow finished.
+	cleanups []func()
+}
+
+// stepFailure is a step which failed, and the error it failed with.
+type stepFailure struct {
+	step string
+	err  error
+}
+
+// terraform returns the executor running TerraForm for the workflow,
+// creating one from the TerraForm binary on disk if none was injected.
+func (m *metadata) terraform() (terraformExecutor, error) {
+	if m.executor == nil {
+		ex, err := newExecutor()
+		if err != nil {
+			return nil, fmt.Errorf("Could not create Terraform executor: %s", err)
+		}
+		// Keep stdout for the events when they are emitted.
+		if m.events != nil {
+			ex.stdout = os.Stderr
+		}
+		m.executor = ex
+	}
+	return m.executor, nil
+}
+
+// output returns the writer the steps print their reports to. It defaults
+// to stdout, or to stderr when events are emitted, so that the events can
+// be parsed from stdout line by line.
+func (m *metadata) output() io.Writer {
+	switch {
+	case m.out != nil:
+		return m.out
+	case m.events != nil:
+		return os.Stderr
+	}
+	return os.Stdout
 }
 
 // Step is the entrypoint of a workflow step implementation.
@@ -22,17 +111,291 @@ type Step func(*metadata) error
 // Workflow is a high-level representation
 // of a set of actions performed in a predictable order.
 type Workflow struct {
+	name     string
 	metadata metadata
 	steps    []Step
+	// from and until are the indexes of the first step to run and of the
+	// step to stop before, if not zero. The steps outside of this range are
+	// skipped, except for the first one, which is always run as it loads
+	// the cluster config into the metadata.
+	from  int
+	until int
+	// resumed is the journal of the run being resumed, if any.
+	resumed *journal
+	// readOnly workflows do not change the cluster directory, and
+	// therefore neither lock nor journal it.
+	readOnly bool
+	// unjournaled workflows lock the cluster directory, but do not journal
+	// it, leaving the journal of the workflow to resume untouched.
+	unjournaled bool
+	// forceUnlock removes the lock of the cluster directory before running.
+	forceUnlock bool
+	// continueOnError makes the workflow run its remaining steps when a step
+	// fails, and retry the failed steps once at the end.
+	continueOnError bool
+	// report, if set, runs once every step ran, even if some failed.
+	report Step
 }
 
 // Execute runs all steps in order.
-func (w Workflow) Execute() error {
+// Workflows running against a cluster directory lock it for their duration.
+// Named workflows running against a cluster directory record the progress
+// of each step in the journal of that directory, unless unjournaled.
+// Cancelling the context interrupts the running step and stops the workflow.
+func (w Workflow) Execute(ctx context.Context) error {
+	w.metadata.ctx = ctx
+
+	var steps []string
 	for _, step := range w.steps {
-		if err := step(&w.metadata); err != nil {
+		steps = append(steps, stepName(step))
+	}
+	start := time.Now()
+	w.metadata.emit(Event{Type: EventWorkflowStarted, Steps: steps})
+
+	err := w.execute(ctx)
+
+	e := Event{
+		Type:            EventWorkflowFinished,
+		Status:          "succeeded",
+		DurationSeconds: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		e.Status = "failed"
+		e.Error = err.Error()
+	}
+	w.metadata.emit(e)
+
+	return err
+}
+
+// WithForceUnlock returns a copy of the workflow which removes a stale lock
+// of the cluster directory before running.
+func (w Workflow) WithForceUnlock() Workflow {
+	w.forceUnlock = true
+	return w
+}
+
+func (w *Workflow) execute(ctx context.Context) error {
+	defer func() {
+		for _, cleanup := range w.metadata.cleanups {
+			cleanup()
+		}
+	}()
+
+	// Workflows running against a cluster directory hold its lock, so that
+	// no other workflow touches the same state files at the same time.
+	if w.metadata.clusterDir != "" && !w.readOnly {
+		if w.forceUnlock {
+			if err := forceUnlock(w.metadata.clusterDir); err != nil {
+				return err
+			}
+		}
+		unlock, err := acquireLock(w.metadata.clusterDir)
+		if err != nil {
 			return err
 		}
+		defer unlock()
+	}
+
+	j, err := w.openJournal()
+	if err != nil {
+		return err
+	}
+
+	var failed []int
+	for i := range w.steps {
+		if i != 0 && (i < w.from || (w.until > 0 && i >= w.until)) {
+			continue
+		}
+		if err := w.runStep(ctx, j, i); err != nil {
+			// The first step loads the cluster config the other steps need.
+			if !w.continueOnError || i == 0 || ctx.Err() != nil {
+				return err
+			}
+			log.Warnf("Step %s failed, continuing: %v", stepName(w.steps[i]), err)
+			failed = append(failed, i)
+		}
+	}
+
+	// Failed steps are retried once, as they may have failed because of
+	// resources the later steps had not released yet.
+	w.metadata.failures = nil
+	for _, i := range failed {
+		log.Infof("Retrying step %s", stepName(w.steps[i]))
+		if err := w.runStep(ctx, j, i); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			w.metadata.failures = append(w.metadata.failures, stepFailure{step: stepName(w.steps[i]), err: err})
+		}
 	}
 
+	if w.report != nil {
+		if err := w.report(&w.metadata); err != nil {
+			return err
+		}
+	}
+	if len(w.metadata.failures) > 0 {
+		var names []string
+		for _, f := range w.metadata.failures {
+			names = append(names, f.step)
+		}
+		if len(names) == 1 {
+			return fmt.Errorf("step %s failed", names[0])
+		}
+		return fmt.Errorf("%d steps failed: %s", len(names), strings.Join(names, ", "))
+	}
 	return nil
 }
+
+// runStep runs the step at the given index, recording its progress in the
+// journal, if any, and in the events.
+func (w *Workflow) runStep(ctx context.Context, j *journal, i int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if j != nil {
+		if err := j.start(i); err != nil {
+			return err
+		}
+	}
+
+	step := w.steps[i]
+	name := stepName(step)
+	start := time.Now()
+	w.metadata.emit(Event{Type: EventStepStarted, Step: name})
+
+	w.metadata.step = name
+	err := step(&w.metadata)
+	w.metadata.step = ""
+	status := stepFinished
+	if err != nil {
+		status = stepFailed
+		if ctx.Err() != nil {
+			status = stepInterrupted
+			log.Warnf("Workflow interrupted during step %s", name)
+		}
+	}
+
+	e := Event{
+		Type:            EventStepFinished,
+		Step:            name,
+		Status:          string(status),
+		DurationSeconds: time.Since(start).Seconds(),
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	w.metadata.emit(e)
+
+	if j != nil {
+		if jerr := j.end(i, status, err); jerr != nil {
+			return jerr
+		}
+	}
+	return err
+}
+
+// openJournal returns the journal to record the workflow progress into,
+// or nil if the workflow is not journaled.
+func (w Workflow) openJournal() (*journal, error) {
+	if w.name == "" || w.metadata.clusterDir == "" || w.readOnly || w.unjournaled {
+		return nil, nil
+	}
+	if w.resumed != nil {
+		return w.resumed, nil
+	}
+	j := newJournal(w.metadata.clusterDir, w.name, w.steps)
+	for i := 1; i < w.from; i++ {
+		j.Steps[i].Status = stepSkipped
+	}
+	return j, nil
+}
+
+// WithSteps returns a copy of the workflow only running the steps from the
+// step named from up to the step named until, both included. Either name
+// can be empty to run from the start or until the end of the workflow.
+// Names match the steps' function names, case-insensitively and with or
+// without their "install"/"destroy" prefix and "Step" suffix, e.g. "etcd"
+// matches installEtcdStep.
+//
+// The first step, which loads the cluster config, is always run.
+// An error is returned if the steps skipped at the start of the workflow
+// did not leave the state the remaining steps require.
+func (w Workflow) WithSteps(from, until string) (Workflow, error) {
+	w.from = 1
+	if from != "" {
+		i, err := w.findStep(from, 1)
+		if err != nil {
+			return Workflow{}, err
+		}
+		w.from = i
+	}
+	if until != "" {
+		i, err := w.findStep(until, w.from)
+		if err != nil {
+			return Workflow{}, err
+		}
+		w.until = i + 1
+	}
+
+	for _, step := range w.steps[1:w.from] {
+		if err := checkSkippedStep(w.metadata.clusterDir, stepName(step)); err != nil {
+			return Workflow{}, err
+		}
+	}
+	return w, nil
+}
+
+// findStep returns the index of the first step matching name, at or after
+// the given index.
+func (w Workflow) findStep(name string, from int) (int, error) {
+	var names []string
+	for _, step := range w.steps[from:] {
+		names = append(names, stepName(step))
+	}
+	for i, n := range names {
+		if normalizeStepName(n) == normalizeStepName(name) {
+			return from + i, nil
+		}
+	}
+	return 0, fmt.Errorf("no step %q in the %s workflow, expected one of: %s", name, w.name, strings.Join(names, ", "))
+}
+
+// checkSkippedStep returns an error if the given step, skipped at the start
+// of a workflow, did not leave the state required by the following steps:
+// the state of skipped install steps must exist, and the state of skipped
+// destroy steps must not hold resources anymore.
+func checkSkippedStep(clusterDir, step string) error {
+	if state, ok := installStepStates[step]; ok {
+		if !hasStateFile(clusterDir, state) {
+			return fmt.Errorf("cannot skip step %s: the %s state file does not exist, run the step first", step, state)
+		}
+	}
+	if state, ok := destroyStepStates[step]; ok && hasStateFile(clusterDir, state) {
+		s, err := readStateFile(clusterDir, state)
+		if err != nil {
+			return err
+		}
+		if n := len(s.resources()); n > 0 {
+			return fmt.Errorf("cannot skip step %s: the %s state file still holds %d resources, run the step first", step, state, n)
+		}
+	}
+	return nil
+}
+
+// normalizeStepName lowercases the name of a step, and drops its
+// "install"/"destroy" prefix, "Step" suffix, dashes and underscores.
+func normalizeStepName(name string) string {
+	n := strings.ToLower(name)
+	n = strings.TrimSuffix(n, "step")
+	n = strings.TrimPrefix(n, "install")
+	n = strings.TrimPrefix(n, "destroy")
+	return strings.NewReplacer("-", "", "_", "").Replace(n)
+}
+
+// stepName returns the name of the function implementing the given step.
+func stepName(step Step) string {
+	name := runtime.FuncForPC(reflect.ValueOf(step).Pointer()).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
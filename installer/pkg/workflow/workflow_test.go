@@ -1,7 +1,14 @@
 package workflow
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -45,9 +52,224 @@ func TestWorkflowTypeExecute(t *testing.T) {
 			metadata: tc.m,
 			steps:    tc.steps,
 		}
-		err := wf.Execute()
+		err := wf.Execute(context.Background())
 		if (err != nil) != tc.expectedError {
 			t.Errorf("Test case %s: WorkflowType.Execute() expected error: %v, got: %v", tc.test, tc.expectedError, (err != nil))
 		}
 	}
 }
+
+func TestWorkflowJournalResume(t *testing.T) {
+	clusterDir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("failed to create cluster dir: %v", err)
+	}
+	defer os.RemoveAll(clusterDir)
+
+	var runs []int
+	fail := true
+	steps := []Step{
+		func(*metadata) error { runs = append(runs, 0); return nil },
+		func(*metadata) error { runs = append(runs, 1); return nil },
+		func(*metadata) error {
+			runs = append(runs, 2)
+			if fail {
+				return errors.New("step failed")
+			}
+			return nil
+		},
+		func(*metadata) error { runs = append(runs, 3); return nil },
+	}
+	resumableWorkflows["test"] = func(dir string) Workflow {
+		return Workflow{name: "test", metadata: metadata{clusterDir: dir}, steps: steps}
+	}
+	defer delete(resumableWorkflows, "test")
+
+	if err := resumableWorkflows["test"](clusterDir).Execute(context.Background()); err == nil {
+		t.Fatal("expected the workflow to fail")
+	}
+
+	j, err := readJournal(clusterDir)
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+	expectedStatus := []stepStatus{stepFinished, stepFinished, stepFailed, stepPending}
+	for i, e := range j.Steps {
+		if e.Status != expectedStatus[i] {
+			t.Errorf("step %d: expected status %s, got %s", i, expectedStatus[i], e.Status)
+		}
+	}
+
+	fail = false
+	runs = nil
+	w, err := ResumeWorkflow(clusterDir)
+	if err != nil {
+		t.Fatalf("failed to resume workflow: %v", err)
+	}
+	if err := w.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to execute resumed workflow: %v", err)
+	}
+	// The first step is always run to load the config.
+	if expected := []int{0, 2, 3}; !reflect.DeepEqual(runs, expected) {
+		t.Errorf("expected steps %v to run on resume, got %v", expected, runs)
+	}
+
+	if _, err := ResumeWorkflow(clusterDir); err == nil {
+		t.Error("expected an error resuming a finished workflow")
+	}
+}
+
+func TestWorkflowInterrupt(t *testing.T) {
+	clusterDir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("failed to create cluster dir: %v", err)
+	}
+	defer os.RemoveAll(clusterDir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ran bool
+	wf := Workflow{
+		name:     "test",
+		metadata: metadata{clusterDir: clusterDir},
+		steps: []Step{
+			func(m *metadata) error {
+				cancel()
+				return m.ctx.Err()
+			},
+			func(*metadata) error { ran = true; return nil },
+		},
+	}
+	if err := wf.Execute(ctx); err == nil {
+		t.Fatal("expected the interrupted workflow to fail")
+	}
+	if ran {
+		t.Error("expected no step to run after the interruption")
+	}
+
+	j, err := readJournal(clusterDir)
+	if err != nil {
+		t.Fatalf("failed to read journal: %v", err)
+	}
+	if j.Steps[0].Status != stepInterrupted {
+		t.Errorf("expected the first step to be %s, got %s", stepInterrupted, j.Steps[0].Status)
+	}
+	if j.Steps[1].Status != stepPending {
+		t.Errorf("expected the second step to be %s, got %s", stepPending, j.Steps[1].Status)
+	}
+}
+
+func TestWorkflowEvents(t *testing.T) {
+	var out bytes.Buffer
+	wf := Workflow{
+		name:  "test",
+		steps: []Step{test1Step, test3Step},
+	}.WithEvents(&out)
+	if err := wf.Execute(context.Background()); err == nil {
+		t.Fatal("expected the workflow to fail")
+	}
+
+	expected := []struct {
+		eventType EventType
+		step      string
+		status    string
+	}{
+		{eventType: EventWorkflowStarted},
+		{eventType: EventStepStarted, step: "test1Step"},
+		{eventType: EventStepFinished, step: "test1Step", status: "finished"},
+		{eventType: EventStepStarted, step: "test3Step"},
+		{eventType: EventStepFinished, step: "test3Step", status: "failed"},
+		{eventType: EventWorkflowFinished, status: "failed"},
+	}
+
+	decoder := json.NewDecoder(&out)
+	for i, exp := range expected {
+		var e Event
+		if err := decoder.Decode(&e); err != nil {
+			t.Fatalf("event %d: failed to decode: %v", i, err)
+		}
+		if e.SchemaVersion != EventSchemaVersion || e.Workflow != "test" {
+			t.Errorf("event %d: expected schema version %d of workflow test, got %d of workflow %q", i, EventSchemaVersion, e.SchemaVersion, e.Workflow)
+		}
+		if e.Type != exp.eventType || e.Step != exp.step || e.Status != exp.status {
+			t.Errorf("event %d: expected %s/%s/%s, got %s/%s/%s", i, exp.eventType, exp.step, exp.status, e.Type, e.Step, e.Status)
+		}
+	}
+	if decoder.More() {
+		t.Error("expected no more events")
+	}
+}
+
+func TestWorkflowEventsOnlyOnStdout(t *testing.T) {
+	testCases := []struct {
+		test     string
+		workflow func(clusterDir string) Workflow
+	}{
+		{
+			test:     "plan",
+			workflow: PlanFullWorkflow,
+		},
+		{
+			test:     "destroy",
+			workflow: func(clusterDir string) Workflow { return DestroyWorkflow(clusterDir, true) },
+		},
+		{
+			test:     "destroy dry run",
+			workflow: DestroyDryRunWorkflow,
+		},
+		{
+			test:     "scale",
+			workflow: ScaleWorkflow,
+		},
+	}
+
+	for _, tc := range testCases {
+		clusterDir, baseDir, cleanup := initTestClusterDir(t)
+		defer cleanup()
+		writeTestState(t, clusterDir, etcdStep, map[string]tfStateResource{
+			"aws_instance.etcd_node.0": testResource("aws_instance", "i-0", nil),
+			"aws_instance.etcd_node.1": testResource("aws_instance", "i-1", nil),
+			"aws_instance.etcd_node.2": testResource("aws_instance", "i-2", nil),
+		})
+		writeTestState(t, clusterDir, mastersStep, map[string]tfStateResource{
+			"aws_autoscaling_group.masters": testResource("aws_autoscaling_group", "masters", map[string]string{"desired_capacity": "2"}),
+		})
+		writeTestState(t, clusterDir, joinWorkersStep, map[string]tfStateResource{
+			"aws_autoscaling_group.workers": testResource("aws_autoscaling_group", "workers", map[string]string{"desired_capacity": "1"}),
+		})
+
+		// The command line emits the events to stdout.
+		stdout, err := ioutil.TempFile("", "stdout")
+		if err != nil {
+			t.Fatalf("failed to create stdout file: %v", err)
+		}
+		defer os.Remove(stdout.Name())
+		orig := os.Stdout
+		os.Stdout = stdout
+		w := tc.workflow(clusterDir).WithEvents(os.Stdout)
+		w.metadata.baseDir = baseDir
+		w.metadata.executor = &fakeExecutor{}
+		err = w.Execute(context.Background())
+		os.Stdout = orig
+		stdout.Close()
+		if err != nil {
+			t.Errorf("test case %s: failed to execute workflow: %v", tc.test, err)
+		}
+
+		data, err := ioutil.ReadFile(stdout.Name())
+		if err != nil {
+			t.Fatalf("failed to read stdout: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		for _, line := range lines {
+			var e Event
+			if err := json.Unmarshal([]byte(line), &e); err != nil || e.Type == "" {
+				t.Errorf("test case %s: expected only events on stdout, got %q", tc.test, line)
+			}
+		}
+		if len(lines) < 2 {
+			t.Errorf("test case %s: expected the workflow events on stdout, got %q", tc.test, data)
+		}
+	}
+}
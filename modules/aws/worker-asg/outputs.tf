@@ -1,5 +1,9 @@
 output "aws_launch_configuration" {
-  value = "${aws_launch_configuration.worker_conf.id}"
+  value = "${join(",", aws_launch_configuration.worker_conf.*.id)}"
+}
+
+output "aws_autoscaling_groups" {
+  value = "${aws_autoscaling_group.workers.*.name}"
 }
 
 output "subnet_ids" {
@@ -27,8 +27,16 @@ variable "ec2_ami" {
   default = ""
 }
 
-variable "instance_count" {
-  type = "string"
+variable "pools" {
+  type = "list"
+
+  description = <<EOF
+The worker pools, each a map with the name, count and ignition config file of
+the pool, and optionally its ec2_type, extra_sg_ids (comma separated),
+iam_role_name, root_volume_iops, root_volume_size and root_volume_type,
+overriding the settings of the module. Every pool is an autoscaling group of
+its own, indexed by position: new pools are appended.
+EOF
 }
 
 variable "subnet_ids" {
@@ -90,6 +98,7 @@ variable "kubeconfig_content" {
   default = ""
 }
 
-variable "user_data_ign" {
-  type = "string"
+variable "ignition_dir" {
+  type        = "string"
+  description = "The directory of the ignition config files of the pools."
 }
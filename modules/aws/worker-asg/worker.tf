@@ -1,6 +1,11 @@
 locals {
   ami_owner = "595879546273"
   arn       = "aws"
+
+  worker_iam_role = "${var.worker_iam_role == "" ?
+    join("|", aws_iam_role.worker_role.*.name) :
+    join("|", data.aws_iam_role.worker_role.*.name)
+  }"
 }
 
 data "aws_ami" "coreos_ami" {
@@ -25,14 +30,18 @@ data "aws_ami" "coreos_ami" {
   }
 }
 
+# The pool named worker keeps the names of the single worker group of the
+# clusters installed before worker pools.
 resource "aws_launch_configuration" "worker_conf" {
-  instance_type        = "${var.ec2_type}"
+  count = "${length(var.pools)}"
+
+  instance_type        = "${lookup(var.pools[count.index], "ec2_type", var.ec2_type)}"
   image_id             = "${coalesce(var.ec2_ami, data.aws_ami.coreos_ami.image_id)}"
-  name_prefix          = "${var.cluster_name}-worker-"
+  name_prefix          = "${var.cluster_name}-worker${lookup(var.pools[count.index], "name") == "worker" ? "" : "-${lookup(var.pools[count.index], "name")}"}-"
   key_name             = "${var.ssh_key}"
-  security_groups      = ["${var.sg_ids}"]
-  iam_instance_profile = "${aws_iam_instance_profile.worker_profile.arn}"
-  user_data            = "${var.user_data_ign}"
+  security_groups      = ["${compact(concat(var.sg_ids, split(",", lookup(var.pools[count.index], "extra_sg_ids", ""))))}"]
+  iam_instance_profile = "${aws_iam_instance_profile.worker_profile.*.arn[count.index]}"
+  user_data            = "${file("${var.ignition_dir}/${lookup(var.pools[count.index], "ignition")}")}"
 
   lifecycle {
     create_before_destroy = true
@@ -44,24 +53,31 @@ resource "aws_launch_configuration" "worker_conf" {
   }
 
   root_block_device {
-    volume_type = "${var.root_volume_type}"
-    volume_size = "${var.root_volume_size}"
-    iops        = "${var.root_volume_type == "io1" ? var.root_volume_iops : 0}"
+    volume_type = "${lookup(var.pools[count.index], "root_volume_type", var.root_volume_type)}"
+    volume_size = "${lookup(var.pools[count.index], "root_volume_size", var.root_volume_size)}"
+    iops        = "${lookup(var.pools[count.index], "root_volume_type", var.root_volume_type) == "io1" ? lookup(var.pools[count.index], "root_volume_iops", var.root_volume_iops) : 0}"
   }
 }
 
 resource "aws_autoscaling_group" "workers" {
-  name                 = "${var.cluster_name}-workers"
-  desired_capacity     = "${var.instance_count}"
-  max_size             = "${var.instance_count * 3}"
-  min_size             = "${var.instance_count}"
-  launch_configuration = "${aws_launch_configuration.worker_conf.id}"
+  count = "${length(var.pools)}"
+
+  name                 = "${var.cluster_name}-workers${lookup(var.pools[count.index], "name") == "worker" ? "" : "-${lookup(var.pools[count.index], "name")}"}"
+  desired_capacity     = "${lookup(var.pools[count.index], "count")}"
+  max_size             = "${lookup(var.pools[count.index], "count") * 3}"
+  min_size             = "${lookup(var.pools[count.index], "count")}"
+  launch_configuration = "${aws_launch_configuration.worker_conf.*.id[count.index]}"
   vpc_zone_identifier  = ["${var.subnet_ids}"]
 
   tags = [
     {
       key                 = "Name"
-      value               = "${var.cluster_name}-worker"
+      value               = "${var.cluster_name}-worker${lookup(var.pools[count.index], "name") == "worker" ? "" : "-${lookup(var.pools[count.index], "name")}"}"
+      propagate_at_launch = true
+    },
+    {
+      key                 = "tectonicNodePool"
+      value               = "${lookup(var.pools[count.index], "name")}"
       propagate_at_launch = true
     },
     {
@@ -82,20 +98,19 @@ resource "aws_autoscaling_group" "workers" {
   }
 }
 
+# Every group is attached to every load balancer.
 resource "aws_autoscaling_attachment" "workers" {
-  count = "${length(var.load_balancers)}"
+  count = "${length(var.load_balancers) * length(var.pools)}"
 
-  autoscaling_group_name = "${aws_autoscaling_group.workers.name}"
-  elb                    = "${var.load_balancers[count.index]}"
+  autoscaling_group_name = "${element(aws_autoscaling_group.workers.*.name, count.index / length(var.load_balancers))}"
+  elb                    = "${var.load_balancers[count.index % length(var.load_balancers)]}"
 }
 
 resource "aws_iam_instance_profile" "worker_profile" {
-  name = "${var.cluster_name}-worker-profile"
+  count = "${length(var.pools)}"
 
-  role = "${var.worker_iam_role == "" ?
-    join("|", aws_iam_role.worker_role.*.name) :
-    join("|", data.aws_iam_role.worker_role.*.name)
-  }"
+  name = "${var.cluster_name}-worker${lookup(var.pools[count.index], "name") == "worker" ? "" : "-${lookup(var.pools[count.index], "name")}"}-profile"
+  role = "${lookup(var.pools[count.index], "iam_role_name", local.worker_iam_role)}"
 }
 
 data "aws_iam_role" "worker_role" {
@@ -19,6 +19,7 @@ resource "libvirt_domain" "etcd" {
 
   name            = "etcd${count.index}"
   memory          = "${var.tectonic_libvirt_etcd_memory}"
+  vcpu            = "${var.tectonic_libvirt_etcd_vcpu}"
   coreos_ignition = "${element(libvirt_ignition.etcd.*.id,count.index)}"
 
   disk {
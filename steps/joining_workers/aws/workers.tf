@@ -26,7 +26,6 @@ module "workers" {
   container_linux_version      = "${module.container_linux.version}"
   ec2_type                     = "${var.tectonic_aws_worker_ec2_type}"
   extra_tags                   = "${var.tectonic_aws_extra_tags}"
-  instance_count               = "${var.tectonic_worker_count}"
   load_balancers               = "${var.tectonic_aws_worker_load_balancers}"
   root_volume_iops             = "${var.tectonic_aws_worker_root_volume_iops}"
   root_volume_size             = "${var.tectonic_aws_worker_root_volume_size}"
@@ -37,5 +36,6 @@ module "workers" {
   worker_iam_role              = "${var.tectonic_aws_worker_iam_role_name}"
   ec2_ami                      = "${var.tectonic_aws_ec2_ami_override}"
   base_domain                  = "${var.tectonic_base_domain}"
-  user_data_ign                = "${file("${path.cwd}/${var.tectonic_ignition_worker}")}"
+  ignition_dir                 = "${path.cwd}"
+  pools                        = "${var.tectonic_worker_pools}"
 }
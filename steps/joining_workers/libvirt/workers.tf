@@ -2,23 +2,30 @@ provider "libvirt" {
   uri = "qemu:///system"
 }
 
+# The domains are named after their pool, and keep their index once applied,
+# see tectonic_libvirt_worker_nodes.
 resource "libvirt_volume" "worker" {
-  count          = "${var.tectonic_worker_count}"
-  name           = "worker${count.index}"
+  count          = "${length(var.tectonic_libvirt_worker_nodes)}"
+  name           = "${element(var.tectonic_libvirt_worker_nodes, count.index)}"
   base_volume_id = "${local.libvirt_base_volume_id}"
 }
 
+# The pool named worker keeps the ignition name of the clusters installed
+# before worker pools.
 resource "libvirt_ignition" "worker" {
-  name    = "worker.ign"
-  content = "${file("${path.cwd}/${var.tectonic_ignition_worker}")}"
+  count = "${length(var.tectonic_worker_pools)}"
+
+  name    = "worker${lookup(var.tectonic_worker_pools[count.index], "name") == "worker" ? "" : "-${lookup(var.tectonic_worker_pools[count.index], "name")}"}.ign"
+  content = "${file("${path.cwd}/${lookup(var.tectonic_worker_pools[count.index], "ignition")}")}"
 }
 
 resource "libvirt_domain" "worker" {
-  count = "${var.tectonic_worker_count}"
+  count = "${length(var.tectonic_libvirt_worker_nodes)}"
 
-  name            = "worker${count.index}"
-  memory          = "${var.tectonic_libvirt_worker_memory}"
-  coreos_ignition = "${libvirt_ignition.worker.id}"
+  name            = "${element(var.tectonic_libvirt_worker_nodes, count.index)}"
+  memory          = "${lookup(var.tectonic_worker_pools[element(var.tectonic_libvirt_worker_pool_nodes, count.index)], "memory", var.tectonic_libvirt_worker_memory)}"
+  vcpu            = "${lookup(var.tectonic_worker_pools[element(var.tectonic_libvirt_worker_pool_nodes, count.index)], "vcpu", var.tectonic_libvirt_worker_vcpu)}"
+  coreos_ignition = "${element(libvirt_ignition.worker.*.id, element(var.tectonic_libvirt_worker_pool_nodes, count.index))}"
 
   disk {
     volume_id = "${element(libvirt_volume.worker.*.id, count.index)}"
@@ -26,7 +33,7 @@ resource "libvirt_domain" "worker" {
 
   network_interface {
     network_id = "${local.libvirt_network_id}"
-    hostname   = "${var.tectonic_cluster_name}-worker-${count.index}"
+    hostname   = "${var.tectonic_cluster_name}-${replace(element(var.tectonic_libvirt_worker_nodes, count.index), "/^worker([0-9]+)$/", "worker-$1")}"
     addresses  = ["${cidrhost(var.tectonic_libvirt_ip_range, var.tectonic_libvirt_first_ip_worker + count.index)}"]
   }
 }
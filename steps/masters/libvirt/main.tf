@@ -31,6 +31,7 @@ resource "libvirt_domain" "master" {
   name = "master${count.index}"
 
   memory = "${var.tectonic_libvirt_master_memory}"
+  vcpu   = "${var.tectonic_libvirt_master_vcpu}"
 
   # Override ignition for the first (bootstrap) node. It can't be re-ignited,
   # but that's okay for us
@@ -53,12 +53,42 @@ variable "tectonic_libvirt_etcd_memory" {
 
 variable "tectonic_libvirt_master_memory" {
   type        = "string"
-  description = "ram to allocate for each etcd node"
+  description = "ram to allocate for each master node"
   default     = "2048"
 }
 
 variable "tectonic_libvirt_worker_memory" {
   type        = "string"
-  description = "ram to allocate for each etcd node"
+  description = "ram to allocate for each worker node"
   default     = "1024"
 }
+
+variable "tectonic_libvirt_worker_nodes" {
+  type        = "list"
+  description = "(internal) the name of each worker node, the nodes of the last apply keeping their index"
+  default     = []
+}
+
+variable "tectonic_libvirt_worker_pool_nodes" {
+  type        = "list"
+  description = "(internal) the index in tectonic_worker_pools of the pool of each worker node"
+  default     = []
+}
+
+variable "tectonic_libvirt_etcd_vcpu" {
+  type        = "string"
+  description = "vcpus to allocate for each etcd node"
+  default     = "1"
+}
+
+variable "tectonic_libvirt_master_vcpu" {
+  type        = "string"
+  description = "vcpus to allocate for each master node"
+  default     = "1"
+}
+
+variable "tectonic_libvirt_worker_vcpu" {
+  type        = "string"
+  description = "vcpus to allocate for each worker node"
+  default     = "1"
+}